@@ -0,0 +1,167 @@
+package pkgexec
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var (
+	versionRegexp    = regexp.MustCompile(`(\d+)(?:\.(\d+))?(?:\.(\d+))?`)
+	constraintRegexp = regexp.MustCompile(`^(=|==|!=|>|>=|<|<=)?\s*(\d+(?:\.\d+){0,2})$`)
+
+	binaryLock   = &sync.Mutex{}
+	pathCache    = make(map[string]string)
+	versionCache = make(map[string]Version)
+)
+
+func lookPath(name string) (string, error) {
+	key := os.Getenv("PATH") + "\x00" + name
+	binaryLock.Lock()
+	path, ok := pathCache[key]
+	binaryLock.Unlock()
+	if ok {
+		return path, nil
+	}
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return "", err
+	}
+	binaryLock.Lock()
+	pathCache[key] = path
+	binaryLock.Unlock()
+	return path, nil
+}
+
+func lookBinary(name string, opts BinaryOptions) (*Binary, error) {
+	path, err := lookPath(name)
+	if err != nil {
+		return nil, err
+	}
+	binary := &Binary{
+		Path: path,
+	}
+	if opts.Constraint == "" {
+		return binary, nil
+	}
+	versionArgs := opts.VersionArgs
+	if len(versionArgs) == 0 {
+		versionArgs = []string{"--version"}
+	}
+	version, err := probeVersion(path, versionArgs)
+	if err != nil {
+		return nil, err
+	}
+	if err := version.Satisfies(opts.Constraint); err != nil {
+		return nil, fmt.Errorf("pkgexec: %s at %s: %s", name, path, err.Error())
+	}
+	binary.Version = version
+	return binary, nil
+}
+
+func probeVersion(path string, versionArgs []string) (Version, error) {
+	key := path + "\x00" + strings.Join(versionArgs, "\x00")
+	binaryLock.Lock()
+	version, ok := versionCache[key]
+	binaryLock.Unlock()
+	if ok {
+		return version, nil
+	}
+	output := bytes.NewBuffer(nil)
+	if err := RunIO(IO{Stdout: output, Stderr: output}, append([]string{path}, versionArgs...)...); err != nil {
+		return Version{}, err
+	}
+	version, err := parseVersion(output.String())
+	if err != nil {
+		return Version{}, fmt.Errorf("pkgexec: could not parse version of %s %s: %s", path, strings.Join(versionArgs, " "), err.Error())
+	}
+	binaryLock.Lock()
+	versionCache[key] = version
+	binaryLock.Unlock()
+	return version, nil
+}
+
+func resetBinaryCache() {
+	binaryLock.Lock()
+	defer binaryLock.Unlock()
+	pathCache = make(map[string]string)
+	versionCache = make(map[string]Version)
+}
+
+func parseVersion(s string) (Version, error) {
+	match := versionRegexp.FindStringSubmatch(s)
+	if match == nil {
+		return Version{}, fmt.Errorf("pkgexec: no version in %q", s)
+	}
+	var parts [3]uint64
+	for i, part := range match[1:] {
+		if part == "" {
+			continue
+		}
+		value, err := strconv.ParseUint(part, 10, 64)
+		if err != nil {
+			return Version{}, err
+		}
+		parts[i] = value
+	}
+	return Version{
+		Major: parts[0],
+		Minor: parts[1],
+		Patch: parts[2],
+	}, nil
+}
+
+func compareVersions(v Version, other Version) int {
+	for _, pair := range [][2]uint64{
+		{v.Major, other.Major},
+		{v.Minor, other.Minor},
+		{v.Patch, other.Patch},
+	} {
+		if pair[0] < pair[1] {
+			return -1
+		}
+		if pair[0] > pair[1] {
+			return 1
+		}
+	}
+	return 0
+}
+
+func satisfies(v Version, constraint string) error {
+	for _, part := range strings.Split(constraint, ",") {
+		part = strings.TrimSpace(part)
+		match := constraintRegexp.FindStringSubmatch(part)
+		if match == nil {
+			return fmt.Errorf("pkgexec: invalid version constraint %q", constraint)
+		}
+		other, err := parseVersion(match[2])
+		if err != nil {
+			return err
+		}
+		compare := compareVersions(v, other)
+		var ok bool
+		switch match[1] {
+		case "", "=", "==":
+			ok = compare == 0
+		case "!=":
+			ok = compare != 0
+		case ">":
+			ok = compare > 0
+		case ">=":
+			ok = compare >= 0
+		case "<":
+			ok = compare < 0
+		case "<=":
+			ok = compare <= 0
+		}
+		if !ok {
+			return fmt.Errorf("version %s does not satisfy %s", v.String(), constraint)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package pkgexec
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseVersion(t *testing.T) {
+	for s, expected := range map[string]Version{
+		"git version 2.20.1":             {2, 20, 1},
+		"go version go1.7.4 linux/amd64": {1, 7, 4},
+		"Docker version 1.13.0-rc3":      {1, 13, 0},
+		"v3":                             {3, 0, 0},
+	} {
+		version, err := ParseVersion(s)
+		require.NoError(t, err)
+		require.Equal(t, expected, version, s)
+	}
+	_, err := ParseVersion("no version here")
+	require.Error(t, err)
+}
+
+func TestVersionSatisfies(t *testing.T) {
+	version := Version{2, 20, 1}
+	for _, constraint := range []string{">=2.20", "2.20.1", ">2, <3", "!=2.20.0", "<=2.20.1"} {
+		require.NoError(t, version.Satisfies(constraint), constraint)
+	}
+	for _, constraint := range []string{">=2.21", "2.20", ">=2, <2.20", ">2.20.1"} {
+		require.Error(t, version.Satisfies(constraint), constraint)
+	}
+	require.Error(t, version.Satisfies("~2.20"))
+}
+
+func TestLookBinary(t *testing.T) {
+	binary, err := LookBinary("sh", BinaryOptions{})
+	require.NoError(t, err)
+	require.NotEqual(t, "", binary.Path)
+	_, err = LookBinary("sh", BinaryOptions{VersionArgs: []string{"-c", "echo tool 1.2.3"}, Constraint: ">=1.3"})
+	require.Error(t, err)
+	binary, err = LookBinary("sh", BinaryOptions{VersionArgs: []string{"-c", "echo tool 1.2.3"}, Constraint: ">=1.2"})
+	require.NoError(t, err)
+	require.Equal(t, Version{1, 2, 3}, binary.Version)
+}
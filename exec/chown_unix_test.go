@@ -0,0 +1,44 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package pkgexec
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestChownWithOptions(t *testing.T) {
+	dirPath, err := ioutil.TempDir("", "pkgexec")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(dirPath)
+	}()
+	filePath := filepath.Join(dirPath, "foo")
+	require.NoError(t, ioutil.WriteFile(filePath, []byte("foo"), 0644))
+	require.NoError(t, Chown(dirPath))
+	// sudo is not run as changing the ownership natively succeeds
+	require.NoError(t, ChownWithOptions(dirPath, os.Getuid(), os.Getgid(), ChownOptions{Recursive: true, Sudo: true}))
+	if os.Getuid() != 0 {
+		// changing ownership to another user requires root
+		return
+	}
+	require.NoError(t, ChownWithOptions(dirPath, 1000, 1001, ChownOptions{Recursive: true}))
+	for _, path := range []string{dirPath, filePath} {
+		fileInfo, err := os.Stat(path)
+		require.NoError(t, err)
+		require.Equal(t, uint32(1000), fileInfo.Sys().(*syscall.Stat_t).Uid)
+		require.Equal(t, uint32(1001), fileInfo.Sys().(*syscall.Stat_t).Gid)
+	}
+}
+
+func TestSudoChownArgs(t *testing.T) {
+	require.Equal(t, []string{"sudo", "chown", "-h", "1:2", "/foo"}, sudoChownArgs("/foo", 1, 2, false))
+	require.Equal(t, []string{"sudo", "chown", "1", "/foo"}, sudoChownArgs("/foo", 1, -1, true))
+	require.Equal(t, []string{"sudo", "chown", "-h", ":2", "/foo"}, sudoChownArgs("/foo", -1, 2, false))
+}
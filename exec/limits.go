@@ -0,0 +1,129 @@
+package pkgexec
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// limitsArgs returns the arguments to run args with the rlimits of limits.
+//
+// The command is looked up before it is wrapped, so that a missing command
+// fails with the same error as without limits, rather than with the exit
+// status of the shell.
+func limitsArgs(limits Limits, args []string) ([]string, error) {
+	var ulimits []string
+	if limits.CPUSeconds > 0 {
+		ulimits = append(ulimits, fmt.Sprintf("ulimit -t %d", limits.CPUSeconds))
+	}
+	if limits.AddressSpaceBytes > 0 {
+		ulimits = append(ulimits, fmt.Sprintf("ulimit -v %d", limits.AddressSpaceBytes/1024))
+	}
+	if limits.OpenFiles > 0 {
+		ulimits = append(ulimits, fmt.Sprintf("ulimit -n %d", limits.OpenFiles))
+	}
+	if limits.DisableCoreDumps {
+		ulimits = append(ulimits, "ulimit -c 0")
+	} else if limits.CoreSizeBytes > 0 {
+		ulimits = append(ulimits, fmt.Sprintf("ulimit -c %d", limits.CoreSizeBytes/512))
+	}
+	if len(ulimits) == 0 {
+		return args, nil
+	}
+	path, err := exec.LookPath(args[0])
+	if err != nil {
+		return nil, err
+	}
+	// the arguments are passed as positional parameters so that they are not interpreted by the shell
+	return append(
+		[]string{
+			"/bin/sh",
+			"-c",
+			fmt.Sprintf(`%s && exec "$@"`, strings.Join(ulimits, " && ")),
+			"sh",
+			path,
+		},
+		args[1:]...,
+	), nil
+}
+
+type outputGuard struct {
+	maxBytes   uint64
+	lock       *sync.Mutex
+	numBytes   uint64
+	process    *os.Process
+	isExceeded bool
+	killed     bool
+}
+
+func newOutputGuard(maxBytes uint64) *outputGuard {
+	return &outputGuard{
+		maxBytes,
+		&sync.Mutex{},
+		0,
+		nil,
+		false,
+		false,
+	}
+}
+
+func (o *outputGuard) wrap(writer io.Writer) io.Writer {
+	return &outputGuardWriter{o, writer}
+}
+
+func (o *outputGuard) setProcess(process *os.Process) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	o.process = process
+	// the output may have been exceeded before the process was set
+	o.killIfExceeded()
+}
+
+func (o *outputGuard) exceeded() bool {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	return o.isExceeded
+}
+
+// returns the number of bytes of p that can be written
+func (o *outputGuard) add(p []byte) int {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	remaining := o.maxBytes - o.numBytes
+	if uint64(len(p)) <= remaining {
+		o.numBytes += uint64(len(p))
+		return len(p)
+	}
+	o.numBytes = o.maxBytes
+	o.isExceeded = true
+	o.killIfExceeded()
+	return int(remaining)
+}
+
+// must be called with lock held
+func (o *outputGuard) killIfExceeded() {
+	if o.isExceeded && o.process != nil && !o.killed {
+		o.killed = true
+		_ = signalProcessGroup(o.process, os.Kill)
+	}
+}
+
+type outputGuardWriter struct {
+	guard  *outputGuard
+	writer io.Writer
+}
+
+func (w *outputGuardWriter) Write(p []byte) (int, error) {
+	allowed := w.guard.add(p)
+	n, err := w.writer.Write(p[:allowed])
+	if err != nil {
+		return n, err
+	}
+	if allowed < len(p) {
+		return n, ErrOutputLimitExceeded
+	}
+	return n, nil
+}
@@ -0,0 +1,67 @@
+package pkgexec
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimitsRlimits(t *testing.T) {
+	stdout := bytes.NewBuffer(nil)
+	require.NoError(
+		t,
+		RunIOLimitsDirPath(
+			IO{Stdout: stdout},
+			Limits{
+				CPUSeconds:        5,
+				AddressSpaceBytes: 1 << 30,
+				OpenFiles:         17,
+				DisableCoreDumps:  true,
+			},
+			"",
+			"sh", "-c", "ulimit -t; ulimit -v; ulimit -n; ulimit -c",
+		),
+	)
+	require.Equal(t, "5\n1048576\n17\n0\n", stdout.String())
+}
+
+func TestLimitsMaxOutputBytes(t *testing.T) {
+	stdout := bytes.NewBuffer(nil)
+	err := RunIOLimitsDirPath(IO{Stdout: stdout}, Limits{MaxOutputBytes: 1000}, "", "yes")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), ErrOutputLimitExceeded.Error())
+	require.Equal(t, 1000, stdout.Len())
+}
+
+func TestLimitsMaxOutputBytesKillsProcessGroup(t *testing.T) {
+	dirPath, err := ioutil.TempDir("", "pkgexec")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(dirPath)
+	}()
+	// the background process is killed along with sh, so it never creates the file
+	err = RunIOLimitsDirPath(IO{}, Limits{MaxOutputBytes: 1000}, dirPath, "sh", "-c", "(sleep 1 && touch alive) & yes")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), ErrOutputLimitExceeded.Error())
+	time.Sleep(2 * time.Second)
+	_, err = os.Stat(filepath.Join(dirPath, "alive"))
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestLimitsMissingCommand(t *testing.T) {
+	err := RunIOLimitsDirPath(IO{}, Limits{}, "", "pkgexec-missing-command")
+	require.Error(t, err)
+	limitsErr := RunIOLimitsDirPath(IO{}, Limits{OpenFiles: 17}, "", "pkgexec-missing-command")
+	require.Error(t, limitsErr)
+	require.Equal(t, err.Error(), limitsErr.Error())
+	require.Equal(t, err.(*CommandError).Err, limitsErr.(*CommandError).Err)
+	require.Equal(t, -1, limitsErr.(*CommandError).ExitCode())
+	require.True(t, errors.Is(limitsErr.(*CommandError).Err, exec.ErrNotFound))
+}
@@ -8,16 +8,28 @@ import (
 	"io/ioutil"
 	"os"
 	"os/exec"
+	"strconv"
 	"strings"
 	"sync"
+	"syscall"
+	"time"
 
 	"go.pedge.io/lion"
 	"go.pedge.io/lion/proto"
+	"go.pedge.io/pkg/file"
 )
 
 var (
 	// ErrNoArgs is the error returned if there are no arguments given.
 	ErrNoArgs = errors.New("pkgexec: no arguments given")
+	// ErrOutputLimitExceeded is the error returned if a command exceeds Limits.MaxOutputBytes.
+	ErrOutputLimitExceeded = errors.New("pkgexec: output limit exceeded")
+	// ErrPTYNotSupported is the error returned if pseudo-terminals are not supported on this platform.
+	ErrPTYNotSupported = errors.New("pkgexec: pseudo-terminals are not supported on this platform")
+	// ErrProcessAlreadyStarted is the error returned if Start is called on a Process more than once.
+	ErrProcessAlreadyStarted = errors.New("pkgexec: process already started")
+	// ErrProcessNotStarted is the error returned if Stop is called on a Process that was not started.
+	ErrProcessNotStarted = errors.New("pkgexec: process not started")
 
 	globalDebug = false
 	lock        = &sync.Mutex{}
@@ -35,6 +47,36 @@ func SetDebug(debug bool) {
 	}
 }
 
+// CommandError is the error returned if a command fails.
+type CommandError struct {
+	// The arguments of the command.
+	Args []string
+	// The error from running the command.
+	Err error
+	// The stderr of the command, or the combined output if
+	// the command was attached to a pseudo-terminal.
+	Output []byte
+}
+
+// Error returns the arguments, the error, and the output if any.
+func (e *CommandError) Error() string {
+	if len(e.Output) > 0 {
+		return fmt.Sprintf("%s: %s\n%s", strings.Join(e.Args, " "), e.Err.Error(), string(e.Output))
+	}
+	return fmt.Sprintf("%s: %s", strings.Join(e.Args, " "), e.Err.Error())
+}
+
+// ExitCode returns the exit code of the command, or -1 if the command
+// did not exit normally, for example if it could not be started or was killed.
+func (e *CommandError) ExitCode() int {
+	if exitErr, ok := e.Err.(*exec.ExitError); ok {
+		if waitStatus, ok := exitErr.Sys().(syscall.WaitStatus); ok && waitStatus.Exited() {
+			return waitStatus.ExitStatus()
+		}
+	}
+	return -1
+}
+
 // IO defines the inputs and outputs for a command.
 type IO struct {
 	Stdin  io.Reader
@@ -88,6 +130,46 @@ func RunIO(ioObj IO, args ...string) error {
 
 // RunIODirPath runs the command with the given IO and arguments in the given directory specified by dirPath.
 func RunIODirPath(ioObj IO, dirPath string, args ...string) error {
+	return RunIOLimitsDirPath(ioObj, Limits{}, dirPath, args...)
+}
+
+// Limits are resource limits applied to a command.
+//
+// The rlimits are set by /bin/sh with ulimit before the command is executed,
+// and are set as both the soft and hard limits, so the command cannot raise them.
+// A shell is used as the rlimits must be in place before the command runs and
+// before it can start other processes, which setting them on the started process
+// with prlimit cannot guarantee, and os/exec cannot set them between fork and exec.
+// As a result, /bin/sh must exist and support ulimit -t, -v, -n and -c. The command
+// is looked up before the shell is run, so a missing command fails with the same
+// error as without limits. No shell is used if no rlimit is set. If a field is not set, the corresponding limit is inherited
+// from the current process.
+type Limits struct {
+	// The maximum CPU time in seconds, see RLIMIT_CPU.
+	CPUSeconds uint64
+	// The maximum size of the virtual memory in bytes, see RLIMIT_AS.
+	// Rounded down to a multiple of 1024.
+	AddressSpaceBytes uint64
+	// The maximum number of open file descriptors, see RLIMIT_NOFILE.
+	OpenFiles uint64
+	// The maximum size of core files in bytes, see RLIMIT_CORE.
+	// Rounded down to a multiple of 512.
+	CoreSizeBytes uint64
+	// DisableCoreDumps sets the maximum size of core files to 0.
+	// Takes precedence over CoreSizeBytes.
+	DisableCoreDumps bool
+	// The maximum number of bytes the command can write to stdout and stderr combined.
+	// If exceeded, the command and the processes it started are killed and
+	// ErrOutputLimitExceeded is returned.
+	// If set, the command is run in a new process group so that the processes
+	// it started can be killed, so it does not receive the signals sent by
+	// a terminal, such as SIGINT on Ctrl-C.
+	MaxOutputBytes uint64
+}
+
+// RunIOLimitsDirPath runs the command with the given IO, limits and arguments in the given
+// directory specified by dirPath.
+func RunIOLimitsDirPath(ioObj IO, limits Limits, dirPath string, args ...string) error {
 	if len(args) == 0 {
 		return ErrNoArgs
 	}
@@ -96,36 +178,374 @@ func RunIODirPath(ioObj IO, dirPath string, args ...string) error {
 	if ioObj.Stderr != nil {
 		stderr = io.MultiWriter(debugStderr, ioObj.Stderr)
 	}
-	cmd := exec.Command(args[0], args[1:]...)
+	stdout := ioObj.Stdout
+	var guard *outputGuard
+	if limits.MaxOutputBytes > 0 {
+		guard = newOutputGuard(limits.MaxOutputBytes)
+		if stdout == nil {
+			stdout = ioutil.Discard
+		}
+		stdout = guard.wrap(stdout)
+		stderr = guard.wrap(stderr)
+	}
+	cmdArgs, err := limitsArgs(limits, args)
+	if err != nil {
+		return commandError(args, err, nil)
+	}
+	cmd := exec.Command(cmdArgs[0], cmdArgs[1:]...)
 	cmd.Stdin = ioObj.Stdin
-	cmd.Stdout = ioObj.Stdout
+	cmd.Stdout = stdout
 	cmd.Stderr = stderr
 	cmd.Dir = dirPath
-	if globalDebug {
-		protolion.Debug(&RunningCommand{Args: strings.Join(args, " ")})
+	if guard != nil {
+		setProcessGroup(cmd)
 	}
-	if err := cmd.Run(); err != nil {
-		data, _ := ioutil.ReadAll(debugStderr)
-		if data != nil && len(data) > 0 {
-			return fmt.Errorf("%s: %s\n%s", strings.Join(args, " "), err.Error(), string(data))
+	logRunningCommand(args)
+	err = cmd.Start()
+	if err == nil {
+		if guard != nil {
+			guard.setProcess(cmd.Process)
 		}
-		return fmt.Errorf("%s: %s", strings.Join(args, " "), err.Error())
+		err = cmd.Wait()
+	}
+	if guard != nil && guard.exceeded() {
+		err = ErrOutputLimitExceeded
+	}
+	if err != nil {
+		data, _ := ioutil.ReadAll(debugStderr)
+		return commandError(args, err, data)
 	}
 	return nil
 }
 
+// WindowSize is the size of a pseudo-terminal.
+type WindowSize struct {
+	Rows uint16
+	Cols uint16
+}
+
+// PTYOptions are options for running a command attached to a pseudo-terminal.
+type PTYOptions struct {
+	// The initial size of the pseudo-terminal.
+	// Default value is 24 rows and 80 columns.
+	Size WindowSize
+	// If set, the pseudo-terminal is resized to each WindowSize received
+	// while the command is running.
+	Resize <-chan WindowSize
+}
+
+// RunPTYOutput runs the command with the given arguments attached to a
+// pseudo-terminal and returns the combined output.
+//
+// Only supported on Linux.
+func RunPTYOutput(opts PTYOptions, args ...string) ([]byte, error) {
+	output := bytes.NewBuffer(nil)
+	err := RunPTYIODirPath(IO{Stdout: output}, opts, "", args...)
+	return output.Bytes(), err
+}
+
+// RunPTYIODirPath runs the command with the given IO and arguments attached to a
+// pseudo-terminal in the given directory specified by dirPath.
+//
+// Stdin is written to the pseudo-terminal until the command exits, and the combined output
+// is streamed to Stdout. A read of Stdin blocked when the command exits cannot be interrupted,
+// so Stdin should be closed by the caller if it may block forever.
+// Stderr is not used, as a pseudo-terminal has a single output.
+// Once the command exits, the output is read until none is left. Output written by
+// background processes after the command exits is only read while it keeps coming
+// within 100ms of the previous output.
+// If the command fails, the error includes the combined output.
+//
+// Only supported on Linux.
+func RunPTYIODirPath(ioObj IO, opts PTYOptions, dirPath string, args ...string) error {
+	if len(args) == 0 {
+		return ErrNoArgs
+	}
+	if opts.Size.Rows == 0 {
+		opts.Size.Rows = 24
+	}
+	if opts.Size.Cols == 0 {
+		opts.Size.Cols = 80
+	}
+	return runPTY(ioObj, opts, dirPath, args)
+}
+
+// ProcessState is the state of a Process.
+type ProcessState int
+
+const (
+	// ProcessStateNotStarted says the Process has not been started.
+	ProcessStateNotStarted ProcessState = iota
+	// ProcessStateRunning says the Process is running.
+	ProcessStateRunning
+	// ProcessStateRestarting says the Process crashed and is waiting to be restarted.
+	ProcessStateRestarting
+	// ProcessStateStopped says the Process was stopped or exited successfully.
+	ProcessStateStopped
+	// ProcessStateFailed says the Process crashed and will not be restarted.
+	ProcessStateFailed
+)
+
+var processStateToString = map[ProcessState]string{
+	ProcessStateNotStarted: "NOT_STARTED",
+	ProcessStateRunning:    "RUNNING",
+	ProcessStateRestarting: "RESTARTING",
+	ProcessStateStopped:    "STOPPED",
+	ProcessStateFailed:     "FAILED",
+}
+
+// String returns the string value of the ProcessState.
+func (p ProcessState) String() string {
+	if s, ok := processStateToString[p]; ok {
+		return s
+	}
+	return fmt.Sprintf("UNKNOWN(%d)", int(p))
+}
+
+// Process is a supervised long-running process.
+//
+// A Process is restarted with exponential backoff when it crashes,
+// until ProcessOptions.MaxRestarts is exhausted. A Process that exits
+// successfully is not restarted.
+type Process interface {
+	// Start starts the process in the background.
+	Start() error
+	// Pid returns the pid of the running process, or 0 if the process is not running.
+	Pid() int
+	// State returns the current state.
+	State() ProcessState
+	// Restarts returns the number of times the process has been restarted.
+	Restarts() int
+	// Stop sends ProcessOptions.StopSignal to the process, and kills the process
+	// if it has not exited after ProcessOptions.StopTimeout.
+	// The process is run in its own process group, and the signals are sent to
+	// the whole group, so that the processes it started are stopped as well.
+	// Blocks until the process has exited.
+	Stop() error
+	// Wait blocks until the process is stopped or will not be restarted anymore.
+	// Returns the last error if the process failed.
+	Wait() error
+}
+
+// ProcessOptions are options for a new Process.
+type ProcessOptions struct {
+	// The directory to run the process in.
+	// If not set, the current directory is used.
+	DirPath string
+	// The environment of the process, in the form key=value.
+	// If not set, the environment of the current process is used.
+	Env []string
+	// Stdout and Stderr are where the output of the process is streamed to.
+	// If Stdout or Stderr is not set, each line of that stream is logged as a ProcessOutput.
+	// Stdin is not used.
+	IO IO
+	// The level each ProcessOutput is logged at.
+	// If lion.LevelNone, the output is discarded.
+	// Default value is lion.LevelDebug, the zero value.
+	OutputLevel lion.Level
+	// The maximum number of times the process is restarted after crashing.
+	// If negative, the process is always restarted.
+	// If 0, the process is never restarted.
+	MaxRestarts int
+	// The backoff before the first restart, doubled for each restart after.
+	// If the process ran for longer than MaxBackoff, the backoff is reset.
+	// Default value is 1s.
+	InitialBackoff time.Duration
+	// The maximum backoff between restarts.
+	// Default value is 1m.
+	MaxBackoff time.Duration
+	// The signal sent to the process on Stop.
+	// Default value is SIGTERM.
+	StopSignal os.Signal
+	// The time to wait after sending StopSignal before killing the process.
+	// Default value is 10s.
+	StopTimeout time.Duration
+}
+
+// NewProcess returns a new Process for the given arguments.
+//
+// The process is not started until Start is called.
+func NewProcess(opts ProcessOptions, args ...string) Process {
+	return newProcess(opts, args...)
+}
+
+// Version is a semantic version.
+//
+// Pre-release and build metadata are not kept.
+type Version struct {
+	Major uint64
+	Minor uint64
+	Patch uint64
+}
+
+// ParseVersion parses the first version in s, such as 2.20.1 in "git version 2.20.1".
+//
+// A missing minor or patch version is 0.
+func ParseVersion(s string) (Version, error) {
+	return parseVersion(s)
+}
+
+// String returns the string value of the Version.
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// Compare returns -1 if v < other, 0 if v == other, and 1 if v > other.
+func (v Version) Compare(other Version) int {
+	return compareVersions(v, other)
+}
+
+// Satisfies returns nil if v satisfies the constraint, and an error otherwise.
+//
+// A constraint is a comma-separated list of an operator and a version, such as ">=2.20, <3".
+// Valid operators are =, !=, >, >=, <, and <=. No operator is the same as =.
+func (v Version) Satisfies(constraint string) error {
+	return satisfies(v, constraint)
+}
+
+// BinaryOptions are options for LookBinary.
+type BinaryOptions struct {
+	// The arguments to pass to the binary to print its version.
+	// Both stdout and stderr are parsed.
+	// Default value is --version.
+	VersionArgs []string
+	// The version constraint to check, see Version.Satisfies.
+	// If not set, the version is not probed.
+	Constraint string
+}
+
+// Binary is a binary found on the PATH.
+type Binary struct {
+	// The absolute path of the binary.
+	Path string
+	// The version of the binary.
+	// Only set if BinaryOptions.Constraint was set.
+	Version Version
+}
+
+// LookPath is exec.LookPath, but the result is cached per value of PATH.
+func LookPath(name string) (string, error) {
+	return lookPath(name)
+}
+
+// LookBinary finds the binary with the given name on the PATH, and checks
+// that its version satisfies the constraint.
+//
+// Both the path and the version are cached.
+func LookBinary(name string, opts BinaryOptions) (*Binary, error) {
+	return lookBinary(name, opts)
+}
+
+// ResetBinaryCache resets the cache used by LookPath and LookBinary.
+func ResetBinaryCache() {
+	resetBinaryCache()
+}
+
+// RetryOptions are options for RetryRunIODirPath.
+type RetryOptions struct {
+	// The maximum number of times the command is run.
+	// If 0 or less, 3 is used.
+	MaxAttempts int
+	// The backoff before the second attempt, doubled for each attempt after.
+	// Each backoff is randomized to between half and all of its value.
+	// Default value is 1s.
+	InitialBackoff time.Duration
+	// The maximum backoff between attempts.
+	// Default value is 30s.
+	MaxBackoff time.Duration
+	// Retryable returns true if a failed attempt should be retried, given its
+	// exit code and stderr. The exit code is -1 if the command did not exit normally.
+	// If not set, every failed attempt is retried.
+	Retryable func(exitCode int, stderr []byte) bool
+}
+
+// RetryRun runs the command with the given arguments, retrying on failure.
+func RetryRun(opts RetryOptions, args ...string) error {
+	return RetryRunIODirPath(IO{}, opts, "", args...)
+}
+
+// RetryRunIODirPath runs the command with the given IO and arguments in the given directory
+// specified by dirPath, retrying on failure with exponential backoff.
+//
+// Each attempt after the first is logged as a RetryingCommand with the error of the previous attempt.
+// Stdin is shared across attempts, so a command that reads stdin should not be retried.
+// Stdout and Stderr receive the output of every attempt.
+// Returns the error of the last attempt.
+func RetryRunIODirPath(ioObj IO, opts RetryOptions, dirPath string, args ...string) error {
+	return retryRun(ioObj, opts, dirPath, args)
+}
+
+// ChownOptions are options for ChownWithOptions.
+type ChownOptions struct {
+	// Recursive says to change the ownership of every file under the filepath as well.
+	Recursive bool
+	// FollowSymlinks says to change the ownership of the files symlinks point to
+	// instead of the symlinks themselves.
+	FollowSymlinks bool
+	// UIDMap maps current uids to new uids, see pkgfile.ChownOptions.
+	UIDMap map[int]int
+	// GIDMap maps current gids to new gids, see pkgfile.ChownOptions.
+	GIDMap map[int]int
+	// Sudo says to fall back to 'sudo chown' for a file if changing
+	// its ownership natively fails with a permission error.
+	// If not set, sudo is never used.
+	Sudo bool
+}
+
 // Chown changes the filepath to be owned by the current user and group.
-// It uses 'sudo chown' and 'sudo chgrp'.
 //
-// TODO(pedge): how to escalate privileges from go?
+// Ownership is changed natively, sudo is never used.
+// Use ChownWithOptions to fall back to sudo.
 func Chown(filePath string) error {
-	uid := fmt.Sprintf("%d", os.Getuid())
-	gid := fmt.Sprintf("%d", os.Getgid())
-	if err := RunIO(IO{Stdout: os.Stderr, Stderr: os.Stderr}, "sudo", "chown", uid, filePath); err != nil {
-		return err
+	return ChownWithOptions(filePath, os.Getuid(), os.Getgid(), ChownOptions{})
+}
+
+// ChownWithOptions changes the filepath to be owned by uid and gid.
+// A uid or gid of -1 means to not change that value.
+func ChownWithOptions(filePath string, uid int, gid int, opts ChownOptions) error {
+	fileOpts := pkgfile.ChownOptions{
+		FollowSymlinks: opts.FollowSymlinks,
+		UIDMap:         opts.UIDMap,
+		GIDMap:         opts.GIDMap,
 	}
-	if err := RunIO(IO{Stdout: os.Stderr, Stderr: os.Stderr}, "sudo", "chgrp", gid, filePath); err != nil {
-		return err
+	if opts.Sudo {
+		fileOpts.Fallback = sudoChown
+	}
+	if opts.Recursive {
+		return pkgfile.ChownTree(filePath, uid, gid, fileOpts)
+	}
+	return pkgfile.Chown(filePath, uid, gid, fileOpts)
+}
+
+func sudoChown(filePath string, uid int, gid int, followSymlinks bool) error {
+	return RunIO(IO{Stdout: os.Stderr, Stderr: os.Stderr}, sudoChownArgs(filePath, uid, gid, followSymlinks)...)
+}
+
+func sudoChownArgs(filePath string, uid int, gid int, followSymlinks bool) []string {
+	owner := ""
+	if uid != -1 {
+		owner = strconv.Itoa(uid)
+	}
+	if gid != -1 {
+		owner = fmt.Sprintf("%s:%d", owner, gid)
+	}
+	args := []string{"sudo", "chown"}
+	if !followSymlinks {
+		args = append(args, "-h")
+	}
+	return append(args, owner, filePath)
+}
+
+func logRunningCommand(args []string) {
+	if globalDebug {
+		protolion.Debug(&RunningCommand{Args: strings.Join(args, " ")})
+	}
+}
+
+func commandError(args []string, err error, output []byte) error {
+	return &CommandError{
+		Args:   args,
+		Err:    err,
+		Output: output,
 	}
-	return nil
 }
@@ -7,6 +7,7 @@ package pkgexec
 import proto "github.com/golang/protobuf/proto"
 import fmt "fmt"
 import math "math"
+import google_protobuf "go.pedge.io/pb/go/google/protobuf"
 
 // Reference imports to suppress errors if they are not otherwise used.
 var _ = proto.Marshal
@@ -35,18 +36,186 @@ func (m *RunningCommand) GetArgs() string {
 	return ""
 }
 
+type ProcessStarted struct {
+	Args     string `protobuf:"bytes,1,opt,name=args" json:"args,omitempty"`
+	Pid      uint32 `protobuf:"varint,2,opt,name=pid" json:"pid,omitempty"`
+	Restarts uint32 `protobuf:"varint,3,opt,name=restarts" json:"restarts,omitempty"`
+}
+
+func (m *ProcessStarted) Reset()                    { *m = ProcessStarted{} }
+func (m *ProcessStarted) String() string            { return proto.CompactTextString(m) }
+func (*ProcessStarted) ProtoMessage()               {}
+func (*ProcessStarted) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+
+func (m *ProcessStarted) GetArgs() string {
+	if m != nil {
+		return m.Args
+	}
+	return ""
+}
+
+func (m *ProcessStarted) GetPid() uint32 {
+	if m != nil {
+		return m.Pid
+	}
+	return 0
+}
+
+func (m *ProcessStarted) GetRestarts() uint32 {
+	if m != nil {
+		return m.Restarts
+	}
+	return 0
+}
+
+type ProcessExited struct {
+	Args     string                    `protobuf:"bytes,1,opt,name=args" json:"args,omitempty"`
+	Pid      uint32                    `protobuf:"varint,2,opt,name=pid" json:"pid,omitempty"`
+	Restarts uint32                    `protobuf:"varint,3,opt,name=restarts" json:"restarts,omitempty"`
+	Error    string                    `protobuf:"bytes,4,opt,name=error" json:"error,omitempty"`
+	Duration *google_protobuf.Duration `protobuf:"bytes,5,opt,name=duration" json:"duration,omitempty"`
+}
+
+func (m *ProcessExited) Reset()                    { *m = ProcessExited{} }
+func (m *ProcessExited) String() string            { return proto.CompactTextString(m) }
+func (*ProcessExited) ProtoMessage()               {}
+func (*ProcessExited) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+
+func (m *ProcessExited) GetArgs() string {
+	if m != nil {
+		return m.Args
+	}
+	return ""
+}
+
+func (m *ProcessExited) GetPid() uint32 {
+	if m != nil {
+		return m.Pid
+	}
+	return 0
+}
+
+func (m *ProcessExited) GetRestarts() uint32 {
+	if m != nil {
+		return m.Restarts
+	}
+	return 0
+}
+
+func (m *ProcessExited) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+func (m *ProcessExited) GetDuration() *google_protobuf.Duration {
+	if m != nil {
+		return m.Duration
+	}
+	return nil
+}
+
+type ProcessOutput struct {
+	Args   string `protobuf:"bytes,1,opt,name=args" json:"args,omitempty"`
+	Pid    uint32 `protobuf:"varint,2,opt,name=pid" json:"pid,omitempty"`
+	Stream string `protobuf:"bytes,3,opt,name=stream" json:"stream,omitempty"`
+	Line   string `protobuf:"bytes,4,opt,name=line" json:"line,omitempty"`
+}
+
+func (m *ProcessOutput) Reset()                    { *m = ProcessOutput{} }
+func (m *ProcessOutput) String() string            { return proto.CompactTextString(m) }
+func (*ProcessOutput) ProtoMessage()               {}
+func (*ProcessOutput) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
+
+func (m *ProcessOutput) GetArgs() string {
+	if m != nil {
+		return m.Args
+	}
+	return ""
+}
+
+func (m *ProcessOutput) GetPid() uint32 {
+	if m != nil {
+		return m.Pid
+	}
+	return 0
+}
+
+func (m *ProcessOutput) GetStream() string {
+	if m != nil {
+		return m.Stream
+	}
+	return ""
+}
+
+func (m *ProcessOutput) GetLine() string {
+	if m != nil {
+		return m.Line
+	}
+	return ""
+}
+
+type RetryingCommand struct {
+	Args          string `protobuf:"bytes,1,opt,name=args" json:"args,omitempty"`
+	Attempt       uint32 `protobuf:"varint,2,opt,name=attempt" json:"attempt,omitempty"`
+	PreviousError string `protobuf:"bytes,3,opt,name=previous_error,json=previousError" json:"previous_error,omitempty"`
+}
+
+func (m *RetryingCommand) Reset()                    { *m = RetryingCommand{} }
+func (m *RetryingCommand) String() string            { return proto.CompactTextString(m) }
+func (*RetryingCommand) ProtoMessage()               {}
+func (*RetryingCommand) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+
+func (m *RetryingCommand) GetArgs() string {
+	if m != nil {
+		return m.Args
+	}
+	return ""
+}
+
+func (m *RetryingCommand) GetAttempt() uint32 {
+	if m != nil {
+		return m.Attempt
+	}
+	return 0
+}
+
+func (m *RetryingCommand) GetPreviousError() string {
+	if m != nil {
+		return m.PreviousError
+	}
+	return ""
+}
+
 func init() {
 	proto.RegisterType((*RunningCommand)(nil), "pkgexec.RunningCommand")
+	proto.RegisterType((*ProcessStarted)(nil), "pkgexec.ProcessStarted")
+	proto.RegisterType((*ProcessExited)(nil), "pkgexec.ProcessExited")
+	proto.RegisterType((*ProcessOutput)(nil), "pkgexec.ProcessOutput")
+	proto.RegisterType((*RetryingCommand)(nil), "pkgexec.RetryingCommand")
 }
 
 func init() { proto.RegisterFile("exec/pkgexec.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 85 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0xe2, 0x12, 0x4a, 0xad, 0x48, 0x4d,
-	0xd6, 0x2f, 0xc8, 0x4e, 0x07, 0xd1, 0x7a, 0x05, 0x45, 0xf9, 0x25, 0xf9, 0x42, 0xec, 0x50, 0xae,
-	0x92, 0x0a, 0x17, 0x5f, 0x50, 0x69, 0x5e, 0x5e, 0x66, 0x5e, 0xba, 0x73, 0x7e, 0x6e, 0x6e, 0x62,
-	0x5e, 0x8a, 0x90, 0x10, 0x17, 0x4b, 0x62, 0x51, 0x7a, 0xb1, 0x04, 0xa3, 0x02, 0xa3, 0x06, 0x67,
-	0x10, 0x98, 0x9d, 0xc4, 0x06, 0xd6, 0x65, 0x0c, 0x08, 0x00, 0x00, 0xff, 0xff, 0x4c, 0xbc, 0xa5,
-	0xb0, 0x4b, 0x00, 0x00, 0x00,
+	// 284 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0xac, 0x50, 0x4d, 0x4b, 0x03, 0x31,
+	0x10, 0x65, 0xed, 0xf7, 0x48, 0xab, 0x04, 0x91, 0xd8, 0x83, 0x94, 0x45, 0xa1, 0xa7, 0x2d, 0x28,
+	0xfe, 0x02, 0xed, 0x59, 0x89, 0x3f, 0x40, 0xd2, 0x76, 0xba, 0x04, 0xbb, 0x49, 0x98, 0x24, 0x52,
+	0xff, 0x8c, 0xbf, 0x55, 0x36, 0x9b, 0xdd, 0x93, 0x88, 0x07, 0x4f, 0x99, 0xf7, 0x78, 0x99, 0x79,
+	0xef, 0x01, 0xc3, 0x23, 0x6e, 0x57, 0xf6, 0xbd, 0xac, 0xdf, 0xc2, 0x92, 0xf1, 0x86, 0x8d, 0x12,
+	0x9c, 0x5f, 0x97, 0xc6, 0x94, 0x07, 0x5c, 0x45, 0x7a, 0x13, 0xf6, 0xab, 0x5d, 0x20, 0xe9, 0x95,
+	0xd1, 0x8d, 0x30, 0xbf, 0x81, 0x99, 0x08, 0x5a, 0x2b, 0x5d, 0x3e, 0x9a, 0xaa, 0x92, 0x7a, 0xc7,
+	0x18, 0xf4, 0x25, 0x95, 0x8e, 0x67, 0x8b, 0x6c, 0x39, 0x11, 0x71, 0xce, 0x05, 0xcc, 0x5e, 0xc8,
+	0x6c, 0xd1, 0xb9, 0x57, 0x2f, 0xc9, 0xe3, 0x8f, 0x2a, 0x76, 0x0e, 0x3d, 0xab, 0x76, 0xfc, 0x64,
+	0x91, 0x2d, 0xa7, 0xa2, 0x1e, 0xd9, 0x1c, 0xc6, 0x84, 0xae, 0xfe, 0xe2, 0x78, 0x2f, 0xd2, 0x1d,
+	0xce, 0xbf, 0x32, 0x98, 0xa6, 0xa5, 0xeb, 0xa3, 0xfa, 0x97, 0x9d, 0xec, 0x02, 0x06, 0x48, 0x64,
+	0x88, 0xf7, 0xe3, 0x8a, 0x06, 0xb0, 0x07, 0x18, 0xb7, 0xa9, 0xf9, 0x60, 0x91, 0x2d, 0x4f, 0xef,
+	0xae, 0x8a, 0xa6, 0x96, 0xa2, 0xad, 0xa5, 0x78, 0x4a, 0x02, 0xd1, 0x49, 0x73, 0xd9, 0xf9, 0x7b,
+	0x0e, 0xde, 0x06, 0xff, 0x47, 0x7f, 0x97, 0x30, 0x74, 0x9e, 0x50, 0x56, 0xd1, 0xdd, 0x44, 0x24,
+	0x54, 0xff, 0x3e, 0x28, 0x8d, 0xc9, 0x5a, 0x9c, 0xf3, 0x3d, 0x9c, 0x09, 0xf4, 0xf4, 0xf9, 0x7b,
+	0xfd, 0x8c, 0xc3, 0x48, 0x7a, 0x8f, 0x95, 0xf5, 0xe9, 0x50, 0x0b, 0xd9, 0x2d, 0xcc, 0x2c, 0xe1,
+	0x87, 0x32, 0xc1, 0xbd, 0x35, 0xc9, 0x9b, 0xa3, 0xd3, 0x96, 0x5d, 0xd7, 0xe4, 0x66, 0x18, 0x73,
+	0xde, 0x7f, 0x0f, 0x00, 0xaa, 0x12, 0x3f, 0xf5, 0x2b, 0x02, 0x00, 0x00,
 }
@@ -0,0 +1,317 @@
+package pkgexec
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"go.pedge.io/lion"
+	"go.pedge.io/lion/proto"
+	"go.pedge.io/pb/go/google/protobuf"
+)
+
+type process struct {
+	args     []string
+	opts     ProcessOptions
+	lock     *sync.Mutex
+	cmd      *exec.Cmd
+	outputs  []*lineLogWriter
+	state    ProcessState
+	restarts int
+	stopping bool
+	stopC    chan struct{}
+	doneC    chan struct{}
+	err      error
+}
+
+func newProcess(opts ProcessOptions, args ...string) *process {
+	if opts.InitialBackoff == 0 {
+		opts.InitialBackoff = time.Second
+	}
+	if opts.MaxBackoff == 0 {
+		opts.MaxBackoff = time.Minute
+	}
+	if opts.StopSignal == nil {
+		opts.StopSignal = syscall.SIGTERM
+	}
+	if opts.StopTimeout == 0 {
+		opts.StopTimeout = 10 * time.Second
+	}
+	return &process{
+		args,
+		opts,
+		&sync.Mutex{},
+		nil,
+		nil,
+		ProcessStateNotStarted,
+		0,
+		false,
+		make(chan struct{}),
+		make(chan struct{}),
+		nil,
+	}
+}
+
+func (p *process) Start() error {
+	if len(p.args) == 0 {
+		return ErrNoArgs
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.state != ProcessStateNotStarted {
+		return ErrProcessAlreadyStarted
+	}
+	if err := p.startCmd(); err != nil {
+		p.state = ProcessStateFailed
+		p.err = err
+		close(p.doneC)
+		return err
+	}
+	go p.supervise()
+	return nil
+}
+
+func (p *process) Pid() int {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.state != ProcessStateRunning {
+		return 0
+	}
+	return p.cmd.Process.Pid
+}
+
+func (p *process) State() ProcessState {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.state
+}
+
+func (p *process) Restarts() int {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.restarts
+}
+
+func (p *process) Stop() error {
+	p.lock.Lock()
+	if p.state == ProcessStateNotStarted {
+		p.lock.Unlock()
+		return ErrProcessNotStarted
+	}
+	if !p.stopping {
+		p.stopping = true
+		close(p.stopC)
+	}
+	cmd := p.cmd
+	running := p.state == ProcessStateRunning
+	p.lock.Unlock()
+	if running {
+		// the process may have exited in the meantime, which the supervisor will handle
+		_ = signalProcessGroup(cmd.Process, p.opts.StopSignal)
+	}
+	select {
+	case <-p.doneC:
+	case <-time.After(p.opts.StopTimeout):
+		_ = signalProcessGroup(cmd.Process, os.Kill)
+		<-p.doneC
+	}
+	return nil
+}
+
+func (p *process) Wait() error {
+	<-p.doneC
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.err
+}
+
+// must be called with lock held
+func (p *process) startCmd() error {
+	cmd := exec.Command(p.args[0], p.args[1:]...)
+	cmd.Dir = p.opts.DirPath
+	cmd.Env = p.opts.Env
+	setProcessGroup(cmd)
+	argsString := strings.Join(p.args, " ")
+	p.outputs = nil
+	if p.opts.IO.Stdout != nil {
+		cmd.Stdout = p.opts.IO.Stdout
+	} else {
+		stdout := newLineLogWriter(argsString, "stdout", p.opts.OutputLevel)
+		p.outputs = append(p.outputs, stdout)
+		cmd.Stdout = stdout
+	}
+	if p.opts.IO.Stderr != nil {
+		cmd.Stderr = p.opts.IO.Stderr
+	} else {
+		stderr := newLineLogWriter(argsString, "stderr", p.opts.OutputLevel)
+		p.outputs = append(p.outputs, stderr)
+		cmd.Stderr = stderr
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	for _, output := range p.outputs {
+		output.setPid(cmd.Process.Pid)
+	}
+	p.cmd = cmd
+	p.state = ProcessStateRunning
+	protolion.Info(
+		&ProcessStarted{
+			Args:     argsString,
+			Pid:      uint32(cmd.Process.Pid),
+			Restarts: uint32(p.restarts),
+		},
+	)
+	return nil
+}
+
+func (p *process) supervise() {
+	defer close(p.doneC)
+	backoff := p.opts.InitialBackoff
+	for {
+		p.lock.Lock()
+		cmd := p.cmd
+		outputs := p.outputs
+		p.lock.Unlock()
+		start := time.Now()
+		// always wait so that the process is reaped
+		err := cmd.Wait()
+		duration := time.Since(start)
+		for _, output := range outputs {
+			output.flush()
+		}
+		p.lock.Lock()
+		processExited := &ProcessExited{
+			Args:     strings.Join(p.args, " "),
+			Pid:      uint32(cmd.Process.Pid),
+			Restarts: uint32(p.restarts),
+			Error:    errorString(err),
+			Duration: google_protobuf.DurationToProto(duration),
+		}
+		if err != nil && !p.stopping {
+			protolion.Error(processExited)
+		} else {
+			protolion.Info(processExited)
+		}
+		if p.stopping || err == nil {
+			p.state = ProcessStateStopped
+			p.lock.Unlock()
+			return
+		}
+		if p.opts.MaxRestarts >= 0 && p.restarts >= p.opts.MaxRestarts {
+			p.state = ProcessStateFailed
+			p.err = err
+			p.lock.Unlock()
+			return
+		}
+		p.state = ProcessStateRestarting
+		p.lock.Unlock()
+		// a process that ran for longer than the maximum backoff is considered to have recovered
+		if duration > p.opts.MaxBackoff {
+			backoff = p.opts.InitialBackoff
+		}
+		select {
+		case <-time.After(backoff):
+		case <-p.stopC:
+		}
+		backoff *= 2
+		if backoff > p.opts.MaxBackoff {
+			backoff = p.opts.MaxBackoff
+		}
+		p.lock.Lock()
+		if p.stopping {
+			p.state = ProcessStateStopped
+			p.lock.Unlock()
+			return
+		}
+		p.restarts++
+		if err := p.startCmd(); err != nil {
+			p.state = ProcessStateFailed
+			p.err = err
+			p.lock.Unlock()
+			return
+		}
+		p.lock.Unlock()
+	}
+}
+
+type lineLogWriter struct {
+	args   string
+	stream string
+	level  lion.Level
+	lock   *sync.Mutex
+	pid    uint32
+	buffer *bytes.Buffer
+}
+
+func newLineLogWriter(args string, stream string, level lion.Level) *lineLogWriter {
+	return &lineLogWriter{
+		args,
+		stream,
+		level,
+		&sync.Mutex{},
+		0,
+		bytes.NewBuffer(nil),
+	}
+}
+
+func (l *lineLogWriter) Write(p []byte) (int, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	_, _ = l.buffer.Write(p)
+	for {
+		index := bytes.IndexByte(l.buffer.Bytes(), '\n')
+		if index < 0 {
+			return len(p), nil
+		}
+		l.log(string(l.buffer.Next(index + 1)[:index]))
+	}
+}
+
+func (l *lineLogWriter) setPid(pid int) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.pid = uint32(pid)
+}
+
+func (l *lineLogWriter) flush() {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.buffer.Len() > 0 {
+		l.log(l.buffer.String())
+		l.buffer.Reset()
+	}
+}
+
+// must be called with lock held
+func (l *lineLogWriter) log(line string) {
+	processOutput := &ProcessOutput{
+		Args:   l.args,
+		Pid:    l.pid,
+		Stream: l.stream,
+		Line:   line,
+	}
+	switch l.level {
+	case lion.LevelDebug:
+		protolion.Debug(processOutput)
+	case lion.LevelInfo:
+		protolion.Info(processOutput)
+	case lion.LevelWarn:
+		protolion.Warn(processOutput)
+	case lion.LevelNone:
+	default:
+		// the output of a process should not exit the current process
+		protolion.Error(processOutput)
+	}
+}
+
+func errorString(err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	return ""
+}
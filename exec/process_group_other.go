@@ -0,0 +1,21 @@
+//go:build windows || plan9
+// +build windows plan9
+
+package pkgexec
+
+import (
+	"os"
+	"os/exec"
+)
+
+// setProcessGroup does nothing, as process groups are not supported on this platform.
+func setProcessGroup(*exec.Cmd) {}
+
+// signalProcessGroup sends signal to process only, as process groups are not
+// supported on this platform.
+func signalProcessGroup(process *os.Process, signal os.Signal) error {
+	if signal == os.Kill {
+		return process.Kill()
+	}
+	return process.Signal(signal)
+}
@@ -0,0 +1,29 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package pkgexec
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup makes the command the leader of a new process group,
+// so that signalProcessGroup also reaches the processes the command starts.
+func setProcessGroup(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+}
+
+// signalProcessGroup sends signal to the process group of process,
+// which must have been started with setProcessGroup.
+func signalProcessGroup(process *os.Process, signal os.Signal) error {
+	sysSignal, ok := signal.(syscall.Signal)
+	if !ok {
+		return process.Signal(signal)
+	}
+	return syscall.Kill(-process.Pid, sysSignal)
+}
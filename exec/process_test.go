@@ -0,0 +1,79 @@
+package pkgexec
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessRestartsUntilBudgetExhausted(t *testing.T) {
+	stdout := bytes.NewBuffer(nil)
+	process := NewProcess(
+		ProcessOptions{
+			IO: IO{
+				Stdout: stdout,
+			},
+			MaxRestarts:    2,
+			InitialBackoff: time.Millisecond,
+		},
+		"sh", "-c", "echo crash; exit 1",
+	)
+	require.NoError(t, process.Start())
+	require.Error(t, process.Wait())
+	require.Equal(t, ProcessStateFailed, process.State())
+	require.Equal(t, 2, process.Restarts())
+	require.Equal(t, "crash\ncrash\ncrash\n", stdout.String())
+}
+
+func TestProcessExitSuccessNotRestarted(t *testing.T) {
+	process := NewProcess(ProcessOptions{MaxRestarts: -1}, "true")
+	require.NoError(t, process.Start())
+	require.NoError(t, process.Wait())
+	require.Equal(t, ProcessStateStopped, process.State())
+	require.Equal(t, 0, process.Restarts())
+}
+
+func TestProcessStop(t *testing.T) {
+	process := NewProcess(
+		ProcessOptions{
+			MaxRestarts: -1,
+			StopTimeout: 5 * time.Second,
+		},
+		"sleep", "60",
+	)
+	require.Equal(t, ErrProcessNotStarted, process.Stop())
+	require.NoError(t, process.Start())
+	require.NotEqual(t, 0, process.Pid())
+	require.Equal(t, ProcessStateRunning, process.State())
+	require.NoError(t, process.Stop())
+	require.NoError(t, process.Wait())
+	require.Equal(t, ProcessStateStopped, process.State())
+	require.Equal(t, 0, process.Pid())
+	require.Equal(t, ErrProcessAlreadyStarted, process.Start())
+}
+
+func TestProcessStopProcessGroup(t *testing.T) {
+	dirPath, err := ioutil.TempDir("", "pkgexec")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(dirPath)
+	}()
+	process := NewProcess(
+		ProcessOptions{
+			DirPath:     dirPath,
+			StopTimeout: 5 * time.Second,
+		},
+		// the background process is stopped along with sh, so it never creates the file
+		"sh", "-c", "(sleep 1 && touch alive) & sleep 60",
+	)
+	require.NoError(t, process.Start())
+	require.NoError(t, process.Stop())
+	time.Sleep(2 * time.Second)
+	_, err = os.Stat(filepath.Join(dirPath, "alive"))
+	require.True(t, os.IsNotExist(err))
+}
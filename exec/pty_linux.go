@@ -0,0 +1,199 @@
+//go:build linux
+// +build linux
+
+package pkgexec
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// the time to wait for more output from the master once the command has exited
+const ptyDrainTimeout = 100 * time.Millisecond
+
+func runPTY(ioObj IO, opts PTYOptions, dirPath string, args []string) error {
+	master, slave, err := openPTY()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = master.Close()
+	}()
+	if err := setWindowSize(master, opts.Size); err != nil {
+		_ = slave.Close()
+		return err
+	}
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = slave
+	cmd.Stdout = slave
+	cmd.Stderr = slave
+	cmd.Dir = dirPath
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Setsid:  true,
+		Setctty: true,
+	}
+	logRunningCommand(args)
+	err = cmd.Start()
+	// the child has its own copy of the slave, and reads from the master
+	// only return EOF once all copies of the slave are closed
+	_ = slave.Close()
+	if err != nil {
+		return commandError(args, err, nil)
+	}
+	doneC := make(chan struct{})
+	defer close(doneC)
+	if ioObj.Stdin != nil {
+		go copyStdin(master, ioObj.Stdin, doneC)
+	}
+	if opts.Resize != nil {
+		go func() {
+			for {
+				select {
+				case size := <-opts.Resize:
+					_ = setWindowSize(master, size)
+				case <-doneC:
+					return
+				}
+			}
+		}()
+	}
+	debugOutput := bytes.NewBuffer(nil)
+	var output io.Writer = debugOutput
+	if ioObj.Stdout != nil {
+		output = io.MultiWriter(debugOutput, ioObj.Stdout)
+	}
+	exitedC := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		copyOutput(output, master, exitedC)
+	}()
+	err = cmd.Wait()
+	close(exitedC)
+	// for the read in progress, see copyOutput
+	if deadlineErr := master.SetReadDeadline(time.Now().Add(ptyDrainTimeout)); deadlineErr != nil {
+		_ = master.Close()
+	}
+	wg.Wait()
+	if err != nil {
+		return commandError(args, err, debugOutput.Bytes())
+	}
+	return nil
+}
+
+// copyOutput copies the output from master to output until reading from master
+// returns EIO, which it does once every copy of the slave is closed.
+//
+// A background process the command started may still hold the slave once the
+// command has exited, so from then on, each read only waits ptyDrainTimeout for
+// more output. The deadline is set before each read, so the output buffered in the
+// pseudo-terminal is always read, however long writing to output takes.
+func copyOutput(output io.Writer, master *os.File, exitedC <-chan struct{}) {
+	buffer := make([]byte, 32*1024)
+	for {
+		select {
+		case <-exitedC:
+			_ = master.SetReadDeadline(time.Now().Add(ptyDrainTimeout))
+		default:
+		}
+		n, err := master.Read(buffer)
+		if n > 0 {
+			if _, writeErr := output.Write(buffer[:n]); writeErr != nil {
+				return
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// copyStdin copies stdin to master until stdin is exhausted or doneC is closed.
+// A read of stdin in progress when doneC is closed cannot be interrupted, so the
+// goroutine returns once that read does, without writing what was read.
+func copyStdin(master *os.File, stdin io.Reader, doneC <-chan struct{}) {
+	buffer := make([]byte, 32*1024)
+	for {
+		n, err := stdin.Read(buffer)
+		select {
+		case <-doneC:
+			return
+		default:
+		}
+		if n > 0 {
+			if _, writeErr := master.Write(buffer[:n]); writeErr != nil {
+				return
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func openPTY() (*os.File, *os.File, error) {
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_NOCTTY|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	var unlock int32
+	if err := ioctl(master, syscall.TIOCSPTLCK, unsafe.Pointer(&unlock)); err != nil {
+		_ = master.Close()
+		return nil, nil, err
+	}
+	var ptyNumber uint32
+	if err := ioctl(master, syscall.TIOCGPTN, unsafe.Pointer(&ptyNumber)); err != nil {
+		_ = master.Close()
+		return nil, nil, err
+	}
+	slave, err := os.OpenFile("/dev/pts/"+strconv.Itoa(int(ptyNumber)), os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		_ = master.Close()
+		return nil, nil, err
+	}
+	return master, slave, nil
+}
+
+func setWindowSize(file *os.File, size WindowSize) error {
+	winsize := struct {
+		Rows   uint16
+		Cols   uint16
+		XPixel uint16
+		YPixel uint16
+	}{
+		size.Rows,
+		size.Cols,
+		0,
+		0,
+	}
+	return ioctl(file, syscall.TIOCSWINSZ, unsafe.Pointer(&winsize))
+}
+
+// ioctl uses the raw fd rather than file.Fd, which puts the file in blocking
+// mode, so that read deadlines on the master keep working.
+func ioctl(file *os.File, request uintptr, arg unsafe.Pointer) error {
+	rawConn, err := file.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var errno syscall.Errno
+	if err := rawConn.Control(
+		func(fd uintptr) {
+			_, _, errno = syscall.Syscall(syscall.SYS_IOCTL, fd, request, uintptr(arg))
+		},
+	); err != nil {
+		return err
+	}
+	if errno != 0 {
+		return os.NewSyscallError("ioctl", errno)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+//go:build linux
+// +build linux
+
+package pkgexec
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunPTYEcho(t *testing.T) {
+	output, err := RunPTYOutput(PTYOptions{}, "echo", "hello")
+	require.NoError(t, err)
+	require.Equal(t, "hello\r\n", string(output))
+
+	output, err = RunPTYOutput(PTYOptions{}, "sh", "-c", "test -t 0 && test -t 1 && stty size")
+	require.NoError(t, err)
+	require.Equal(t, "24 80\r\n", string(output))
+	output, err = RunPTYOutput(PTYOptions{Size: WindowSize{Rows: 40, Cols: 120}}, "stty", "size")
+	require.NoError(t, err)
+	require.Equal(t, "40 120\r\n", string(output))
+
+	_, err = RunPTYOutput(PTYOptions{}, "sh", "-c", "echo oops; exit 1")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "oops")
+}
+
+func TestRunPTYStdin(t *testing.T) {
+	output := bytes.NewBuffer(nil)
+	require.NoError(
+		t,
+		RunPTYIODirPath(
+			IO{
+				Stdin:  strings.NewReader("foo\n"),
+				Stdout: output,
+			},
+			PTYOptions{},
+			"",
+			"sh", "-c", "read line; echo got $line",
+		),
+	)
+	require.Contains(t, output.String(), "got foo\r\n")
+
+	// a Stdin that never returns does not block the command from finishing
+	reader, writer := io.Pipe()
+	defer func() {
+		_ = writer.Close()
+	}()
+	output = bytes.NewBuffer(nil)
+	require.NoError(t, RunPTYIODirPath(IO{Stdin: reader, Stdout: output}, PTYOptions{}, "", "echo", "hello"))
+	require.Equal(t, "hello\r\n", output.String())
+}
+
+func TestRunPTYBackgroundProcess(t *testing.T) {
+	start := time.Now()
+	output, err := RunPTYOutput(PTYOptions{}, "sh", "-c", "(trap '' HUP; sleep 30) & echo started")
+	require.NoError(t, err)
+	require.Equal(t, "started\r\n", string(output))
+	require.True(t, time.Since(start) < 10*time.Second)
+}
+
+type testSlowWriter struct {
+	buffer *bytes.Buffer
+}
+
+func (w *testSlowWriter) Write(p []byte) (int, error) {
+	time.Sleep(150 * time.Millisecond)
+	return w.buffer.Write(p)
+}
+
+func TestRunPTYSlowStdout(t *testing.T) {
+	// the output left in the pseudo-terminal when the command exits is read
+	// even if writing it to Stdout takes longer than ptyDrainTimeout
+	output := bytes.NewBuffer(nil)
+	require.NoError(t, RunPTYIODirPath(IO{Stdout: &testSlowWriter{output}}, PTYOptions{}, "", "seq", "1", "3000"))
+	require.True(t, strings.HasSuffix(output.String(), "\r\n2999\r\n3000\r\n"))
+}
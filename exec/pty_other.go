@@ -0,0 +1,8 @@
+//go:build !linux
+// +build !linux
+
+package pkgexec
+
+func runPTY(ioObj IO, opts PTYOptions, dirPath string, args []string) error {
+	return ErrPTYNotSupported
+}
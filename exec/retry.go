@@ -0,0 +1,64 @@
+package pkgexec
+
+import (
+	"math/rand"
+	"strings"
+	"time"
+
+	"go.pedge.io/lion/proto"
+)
+
+func retryRun(ioObj IO, opts RetryOptions, dirPath string, args []string) error {
+	if len(args) == 0 {
+		return ErrNoArgs
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 3
+	}
+	if opts.InitialBackoff == 0 {
+		opts.InitialBackoff = time.Second
+	}
+	if opts.MaxBackoff == 0 {
+		opts.MaxBackoff = 30 * time.Second
+	}
+	backoff := opts.InitialBackoff
+	var err error
+	for attempt := 1; attempt <= opts.MaxAttempts; attempt++ {
+		if attempt > 1 {
+			time.Sleep(jitter(backoff))
+			backoff *= 2
+			if backoff > opts.MaxBackoff {
+				backoff = opts.MaxBackoff
+			}
+			protolion.Info(
+				&RetryingCommand{
+					Args:          strings.Join(args, " "),
+					Attempt:       uint32(attempt),
+					PreviousError: errorString(err),
+				},
+			)
+		}
+		if err = RunIODirPath(ioObj, dirPath, args...); err == nil {
+			return nil
+		}
+		commandErr, ok := err.(*CommandError)
+		if !ok {
+			return err
+		}
+		if attempt == opts.MaxAttempts {
+			return err
+		}
+		if opts.Retryable != nil && !opts.Retryable(commandErr.ExitCode(), commandErr.Output) {
+			return err
+		}
+	}
+	return err
+}
+
+func jitter(backoff time.Duration) time.Duration {
+	half := int64(backoff / 2)
+	if half <= 0 {
+		return backoff
+	}
+	return time.Duration(half + rand.Int63n(half+1))
+}
@@ -0,0 +1,83 @@
+package pkgexec
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryRun(t *testing.T) {
+	dirPath, err := ioutil.TempDir("", "pkgexec")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dirPath) }()
+	attempts := func() int {
+		data, err := ioutil.ReadFile(filepath.Join(dirPath, "attempts"))
+		require.NoError(t, err)
+		return strings.Count(string(data), "x")
+	}
+	reset := func() {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dirPath, "attempts"), nil, 0644))
+	}
+	opts := RetryOptions{
+		MaxAttempts:    4,
+		InitialBackoff: 10 * time.Millisecond,
+		MaxBackoff:     20 * time.Millisecond,
+	}
+
+	// fails on the first two attempts
+	reset()
+	require.NoError(t, RetryRunIODirPath(IO{}, opts, dirPath, "sh", "-c", `echo x >> attempts; test "$(wc -c < attempts)" -gt 4`))
+	require.Equal(t, 3, attempts())
+
+	// backoffs of 10ms, 20ms and 20ms, each randomized to at least half
+	reset()
+	start := time.Now()
+	err = RetryRunIODirPath(IO{}, opts, dirPath, "sh", "-c", "echo x >> attempts; echo oops >&2; exit 3")
+	require.True(t, time.Since(start) >= 25*time.Millisecond)
+	require.Equal(t, 4, attempts())
+	commandErr, ok := err.(*CommandError)
+	require.True(t, ok)
+	require.Equal(t, 3, commandErr.ExitCode())
+	require.Equal(t, "oops\n", string(commandErr.Output))
+
+	reset()
+	opts.Retryable = func(exitCode int, stderr []byte) bool {
+		return exitCode != 3
+	}
+	require.Error(t, RetryRunIODirPath(IO{}, opts, dirPath, "sh", "-c", "echo x >> attempts; exit 3"))
+	require.Equal(t, 1, attempts())
+
+	// a negative MaxAttempts is the default
+	reset()
+	require.Error(t, RetryRunIODirPath(IO{}, RetryOptions{MaxAttempts: -1, InitialBackoff: time.Millisecond}, dirPath, "sh", "-c", "echo x >> attempts; exit 1"))
+	require.Equal(t, 3, attempts())
+
+	require.Equal(t, ErrNoArgs, RetryRun(RetryOptions{}))
+}
+
+func TestJitter(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		backoff := jitter(time.Second)
+		require.True(t, backoff >= 500*time.Millisecond && backoff <= time.Second, backoff.String())
+	}
+	require.Equal(t, time.Duration(1), jitter(1))
+}
+
+func TestCommandError(t *testing.T) {
+	err := RunStderr(ioutil.Discard, "sh", "-c", "echo oops >&2; exit 2")
+	commandErr, ok := err.(*CommandError)
+	require.True(t, ok)
+	require.Equal(t, []string{"sh", "-c", "echo oops >&2; exit 2"}, commandErr.Args)
+	require.Equal(t, 2, commandErr.ExitCode())
+	require.Equal(t, "sh -c echo oops >&2; exit 2: exit status 2\noops\n", commandErr.Error())
+
+	err = Run("pkgexec-does-not-exist")
+	commandErr, ok = err.(*CommandError)
+	require.True(t, ok)
+	require.Equal(t, -1, commandErr.ExitCode())
+}
@@ -0,0 +1,12 @@
+//go:build windows || plan9
+// +build windows plan9
+
+package pkgfile
+
+import "os"
+
+// fileOwner returns false, as files have no uid and gid on this platform,
+// so UIDMap and GIDMap are never applied.
+func fileOwner(os.FileInfo) (int, int, bool) {
+	return 0, 0, false
+}
@@ -0,0 +1,18 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package pkgfile
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileOwner returns the uid and gid of the file of fileInfo.
+func fileOwner(fileInfo os.FileInfo) (int, int, bool) {
+	sysStat, ok := fileInfo.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, false
+	}
+	return int(sysStat.Uid), int(sysStat.Gid), true
+}
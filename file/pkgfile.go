@@ -9,6 +9,79 @@ import (
 	"strings"
 )
 
+// ChownOptions are options for Chown and ChownTree.
+type ChownOptions struct {
+	// FollowSymlinks says to change the ownership of the files symlinks point to
+	// instead of the symlinks themselves.
+	// Symlinked directories are never descended into.
+	FollowSymlinks bool
+	// UIDMap maps current uids to new uids.
+	// If the current uid of a file is in UIDMap, the mapped uid is used
+	// instead of the given uid.
+	// If not set, the given uid is always used.
+	// Not applied on platforms without uids, such as Windows.
+	UIDMap map[int]int
+	// GIDMap maps current gids to new gids, the same as UIDMap.
+	// If not set, the given gid is always used.
+	GIDMap map[int]int
+	// Fallback is called if changing the ownership of a file fails with a permission error.
+	// The uid and gid given are the ones that were attempted.
+	// If not set, the permission error is returned.
+	Fallback func(filePath string, uid int, gid int, followSymlinks bool) error
+}
+
+// Chown changes the ownership of filePath to uid and gid.
+// A uid or gid of -1 means to not change that value.
+func Chown(filePath string, uid int, gid int, opts ChownOptions) error {
+	return chown(filePath, uid, gid, opts)
+}
+
+// ChownTree is Chown for filePath and every file under filePath.
+func ChownTree(filePath string, uid int, gid int, opts ChownOptions) error {
+	return filepath.Walk(
+		filePath,
+		func(path string, _ os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			return chown(path, uid, gid, opts)
+		},
+	)
+}
+
+// ChmodOptions are options for Chmod and ChmodTree.
+type ChmodOptions struct {
+	// FollowSymlinks says to change the mode of the files symlinks point to.
+	// Otherwise, symlinks are skipped, as their mode cannot be changed.
+	// Symlinked directories are never descended into.
+	FollowSymlinks bool
+	// DirMode is the mode to use for directories.
+	// If not set, the given mode is used for directories as well.
+	DirMode os.FileMode
+}
+
+// Chmod changes the mode of filePath.
+func Chmod(filePath string, mode os.FileMode, opts ChmodOptions) error {
+	fileInfo, err := os.Lstat(filePath)
+	if err != nil {
+		return err
+	}
+	return chmod(filePath, fileInfo, mode, opts)
+}
+
+// ChmodTree is Chmod for filePath and every file under filePath.
+func ChmodTree(filePath string, mode os.FileMode, opts ChmodOptions) error {
+	return filepath.Walk(
+		filePath,
+		func(path string, fileInfo os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			return chmod(path, fileInfo, mode, opts)
+		},
+	)
+}
+
 // EditFile edits the file using f and saves it.
 func EditFile(filePath string, f func(io.Reader, io.Writer) error) (retErr error) {
 	tempFilePath := filepath.Join(os.TempDir(), fmt.Sprintf("%s.%d", filepath.Base(filePath), os.Getpid()))
@@ -76,3 +149,52 @@ func editStripPackageCommentsForFile(reader io.Reader, writer io.Writer) error {
 	}
 	return nil
 }
+
+func chown(filePath string, uid int, gid int, opts ChownOptions) error {
+	stat := os.Lstat
+	lchown := os.Lchown
+	if opts.FollowSymlinks {
+		stat = os.Stat
+		lchown = os.Chown
+	}
+	if opts.UIDMap != nil || opts.GIDMap != nil {
+		fileInfo, err := stat(filePath)
+		if err != nil {
+			return err
+		}
+		if currentUID, currentGID, ok := fileOwner(fileInfo); ok {
+			if newUID, ok := opts.UIDMap[currentUID]; ok {
+				uid = newUID
+			}
+			if newGID, ok := opts.GIDMap[currentGID]; ok {
+				gid = newGID
+			}
+		}
+	}
+	if uid == -1 && gid == -1 {
+		return nil
+	}
+	if err := lchown(filePath, uid, gid); err != nil {
+		if os.IsPermission(err) && opts.Fallback != nil {
+			return opts.Fallback(filePath, uid, gid, opts.FollowSymlinks)
+		}
+		return err
+	}
+	return nil
+}
+
+func chmod(filePath string, fileInfo os.FileInfo, mode os.FileMode, opts ChmodOptions) error {
+	if fileInfo.Mode()&os.ModeSymlink != 0 {
+		if !opts.FollowSymlinks {
+			return nil
+		}
+		var err error
+		if fileInfo, err = os.Stat(filePath); err != nil {
+			return err
+		}
+	}
+	if fileInfo.IsDir() && opts.DirMode != 0 {
+		mode = opts.DirMode
+	}
+	return os.Chmod(filePath, mode)
+}
@@ -0,0 +1,135 @@
+package pkgfile
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestChmodTree(t *testing.T) {
+	dirPath := testTree(t)
+	require.NoError(t, ChmodTree(dirPath, 0600, ChmodOptions{DirMode: 0700}))
+	testRequireMode(t, dirPath, 0700)
+	testRequireMode(t, filepath.Join(dirPath, "a"), 0600)
+	testRequireMode(t, filepath.Join(dirPath, "b"), 0700)
+	testRequireMode(t, filepath.Join(dirPath, "b", "c"), 0600)
+
+	// symlinks are skipped unless FollowSymlinks is set
+	require.NoError(t, Chmod(filepath.Join(dirPath, "link"), 0640, ChmodOptions{}))
+	testRequireMode(t, filepath.Join(dirPath, "a"), 0600)
+	require.NoError(t, Chmod(filepath.Join(dirPath, "link"), 0640, ChmodOptions{FollowSymlinks: true}))
+	testRequireMode(t, filepath.Join(dirPath, "a"), 0640)
+
+	require.NoError(t, Chmod(filepath.Join(dirPath, "b"), 0750, ChmodOptions{}))
+	testRequireMode(t, filepath.Join(dirPath, "b"), 0750)
+	require.Error(t, Chmod(filepath.Join(dirPath, "missing"), 0600, ChmodOptions{}))
+}
+
+func TestChownTreeCurrentUser(t *testing.T) {
+	dirPath := testTree(t)
+	uid, gid := os.Getuid(), os.Getgid()
+	require.NoError(t, ChownTree(dirPath, uid, gid, ChownOptions{}))
+	require.NoError(t, ChownTree(dirPath, -1, -1, ChownOptions{}))
+	require.NoError(t, Chown(filepath.Join(dirPath, "link"), uid, -1, ChownOptions{FollowSymlinks: true}))
+	// the current uid and gid map to themselves
+	require.NoError(
+		t,
+		ChownTree(
+			dirPath,
+			-1,
+			-1,
+			ChownOptions{
+				UIDMap: map[int]int{uid: uid},
+				GIDMap: map[int]int{gid: gid},
+			},
+		),
+	)
+	for _, name := range []string{"", "a", "b", "b/c"} {
+		testRequireOwner(t, filepath.Join(dirPath, name), uid, gid)
+	}
+	require.Error(t, Chown(filepath.Join(dirPath, "missing"), uid, gid, ChownOptions{}))
+}
+
+func TestChownMap(t *testing.T) {
+	if os.Getuid() != 0 {
+		t.Skip("changing ownership to another user requires root")
+	}
+	dirPath := testTree(t)
+	require.NoError(t, ChownTree(dirPath, 1000, 1000, ChownOptions{}))
+	require.NoError(t, Chown(filepath.Join(dirPath, "a"), 1001, 1001, ChownOptions{}))
+	require.NoError(
+		t,
+		ChownTree(
+			dirPath,
+			-1,
+			-1,
+			ChownOptions{
+				UIDMap: map[int]int{1000: 2000},
+				GIDMap: map[int]int{1001: 2001},
+			},
+		),
+	)
+	testRequireOwner(t, dirPath, 2000, 1000)
+	testRequireOwner(t, filepath.Join(dirPath, "a"), 1001, 2001)
+	testRequireOwner(t, filepath.Join(dirPath, "b", "c"), 2000, 1000)
+}
+
+func TestChownFallback(t *testing.T) {
+	if os.Getuid() == 0 {
+		t.Skip("root is never denied changing ownership")
+	}
+	dirPath := testTree(t)
+	filePath := filepath.Join(dirPath, "a")
+	err := Chown(filePath, 0, 0, ChownOptions{})
+	require.Error(t, err)
+	require.True(t, os.IsPermission(err))
+	var fallbackArgs []interface{}
+	fallbackErr := errors.New("foo")
+	err = Chown(
+		filePath,
+		0,
+		-1,
+		ChownOptions{
+			Fallback: func(filePath string, uid int, gid int, followSymlinks bool) error {
+				fallbackArgs = []interface{}{filePath, uid, gid, followSymlinks}
+				return fallbackErr
+			},
+		},
+	)
+	require.Equal(t, fallbackErr, err)
+	require.Equal(t, []interface{}{filePath, 0, -1, false}, fallbackArgs)
+}
+
+// testTree creates a temporary directory with the files a and b/c,
+// and a symlink link to a.
+func testTree(t *testing.T) string {
+	dirPath, err := ioutil.TempDir("", "pkgfile")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = os.RemoveAll(dirPath)
+	})
+	require.NoError(t, os.Mkdir(filepath.Join(dirPath, "b"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dirPath, "a"), []byte("a"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dirPath, "b", "c"), []byte("c"), 0644))
+	require.NoError(t, os.Symlink("a", filepath.Join(dirPath, "link")))
+	return dirPath
+}
+
+func testRequireMode(t *testing.T, filePath string, mode os.FileMode) {
+	fileInfo, err := os.Stat(filePath)
+	require.NoError(t, err)
+	require.Equal(t, mode, fileInfo.Mode().Perm())
+}
+
+func testRequireOwner(t *testing.T, filePath string, uid int, gid int) {
+	fileInfo, err := os.Lstat(filePath)
+	require.NoError(t, err)
+	fileUID, fileGID, ok := fileOwner(fileInfo)
+	require.True(t, ok)
+	require.Equal(t, uid, fileUID)
+	require.Equal(t, gid, fileGID)
+}
@@ -0,0 +1,251 @@
+package pkghttp
+
+import (
+	"context"
+	"encoding"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.pedge.io/pb/go/google/type"
+	"go.pedge.io/pb/go/pb/money"
+)
+
+const (
+	defaultBindTimeLayout = time.RFC3339
+	defaultBindDateLayout = "2006-01-02"
+	bindMaxMemory         = 32 << 20
+)
+
+var (
+	bindSources = []string{"path", "query", "form", "header"}
+
+	errBindRequired = errors.New("required")
+
+	timeType            = reflect.TypeOf(time.Time{})
+	durationType        = reflect.TypeOf(time.Duration(0))
+	moneyType           = reflect.TypeOf(&pbmoney.Money{})
+	dateType            = reflect.TypeOf(&google_type.Date{})
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+type pathValuesKey struct{}
+
+func withPathValues(request *http.Request, pathValues map[string]string) *http.Request {
+	merged := make(map[string]string)
+	for key, value := range getPathValues(request.Context()) {
+		merged[key] = value
+	}
+	for key, value := range pathValues {
+		merged[key] = value
+	}
+	return request.WithContext(context.WithValue(request.Context(), pathValuesKey{}, merged))
+}
+
+func getPathValues(ctx context.Context) map[string]string {
+	pathValues, _ := ctx.Value(pathValuesKey{}).(map[string]string)
+	return pathValues
+}
+
+type binder struct {
+	request    *http.Request
+	formParsed bool
+	bindError  *BindError
+}
+
+func bind(request *http.Request, dst interface{}) error {
+	value := reflect.ValueOf(dst)
+	if value.Kind() != reflect.Ptr || value.IsNil() || value.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("pkghttp: Bind requires a non-nil pointer to a struct, got %T", dst)
+	}
+	b := &binder{request, false, &BindError{}}
+	if err := b.bindStruct(value.Elem()); err != nil {
+		return err
+	}
+	if len(b.bindError.FieldErrors) > 0 {
+		return b.bindError
+	}
+	return nil
+}
+
+func (b *binder) bindStruct(value reflect.Value) error {
+	structType := value.Type()
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			if err := b.bindStruct(value.Field(i)); err != nil {
+				return err
+			}
+			continue
+		}
+		// unexported
+		if field.PkgPath != "" {
+			continue
+		}
+		firstSource := ""
+		found := false
+		for _, source := range bindSources {
+			key, ok := field.Tag.Lookup(source)
+			if !ok || key == "" || key == "-" {
+				continue
+			}
+			if firstSource == "" {
+				firstSource = source
+			}
+			values, err := b.values(source, key)
+			if err != nil {
+				return err
+			}
+			if len(values) == 0 {
+				continue
+			}
+			found = true
+			if err := setValues(value.Field(i), values, field.Tag.Get("layout")); err != nil {
+				b.addFieldError(field.Name, source, key, strings.Join(values, ","), err)
+			}
+			break
+		}
+		if !found && firstSource != "" && field.Tag.Get("required") == "true" {
+			b.addFieldError(field.Name, firstSource, field.Tag.Get(firstSource), "", errBindRequired)
+		}
+	}
+	return nil
+}
+
+func (b *binder) values(source string, key string) ([]string, error) {
+	switch source {
+	case "path":
+		if value, ok := getPathValues(b.request.Context())[key]; ok {
+			return []string{value}, nil
+		}
+		return nil, nil
+	case "query":
+		if b.request.URL == nil {
+			return nil, nil
+		}
+		return b.request.URL.Query()[key], nil
+	case "form":
+		if !b.formParsed {
+			if err := b.request.ParseMultipartForm(bindMaxMemory); err != nil && err != http.ErrNotMultipart {
+				return nil, err
+			}
+			b.formParsed = true
+		}
+		return b.request.Form[key], nil
+	case "header":
+		return b.request.Header[http.CanonicalHeaderKey(key)], nil
+	default:
+		return nil, nil
+	}
+}
+
+func (b *binder) addFieldError(field string, source string, key string, value string, err error) {
+	b.bindError.FieldErrors = append(
+		b.bindError.FieldErrors,
+		&FieldError{
+			Field:  field,
+			Source: source,
+			Key:    key,
+			Value:  value,
+			Err:    err,
+		},
+	)
+}
+
+func setValues(value reflect.Value, values []string, layout string) error {
+	// a slice that is a TextUnmarshaler, such as net.IP, is a single value
+	if value.Kind() == reflect.Slice && !reflect.PtrTo(value.Type()).Implements(textUnmarshalerType) {
+		slice := reflect.MakeSlice(value.Type(), len(values), len(values))
+		for i, element := range values {
+			if err := setValue(slice.Index(i), element, layout); err != nil {
+				return err
+			}
+		}
+		value.Set(slice)
+		return nil
+	}
+	return setValue(value, values[0], layout)
+}
+
+func setValue(value reflect.Value, s string, layout string) error {
+	switch value.Type() {
+	case timeType:
+		if layout == "" {
+			layout = defaultBindTimeLayout
+		}
+		t, err := time.Parse(layout, s)
+		if err != nil {
+			return err
+		}
+		value.Set(reflect.ValueOf(t))
+		return nil
+	case durationType:
+		duration, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		value.SetInt(int64(duration))
+		return nil
+	case moneyType:
+		money, err := ParseMoney(s)
+		if err != nil {
+			return err
+		}
+		value.Set(reflect.ValueOf(money))
+		return nil
+	case dateType:
+		if layout == "" {
+			layout = defaultBindDateLayout
+		}
+		date, err := ParseDate(s, layout)
+		if err != nil {
+			return err
+		}
+		value.Set(reflect.ValueOf(date))
+		return nil
+	}
+	if value.CanAddr() && value.Addr().Type().Implements(textUnmarshalerType) {
+		return value.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+	switch value.Kind() {
+	case reflect.Ptr:
+		elem := reflect.New(value.Type().Elem())
+		if err := setValue(elem.Elem(), s, layout); err != nil {
+			return err
+		}
+		value.Set(elem)
+	case reflect.String:
+		value.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		value.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, value.Type().Bits())
+		if err != nil {
+			return err
+		}
+		value.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(s, 10, value.Type().Bits())
+		if err != nil {
+			return err
+		}
+		value.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, value.Type().Bits())
+		if err != nil {
+			return err
+		}
+		value.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported type %s", value.Type())
+	}
+	return nil
+}
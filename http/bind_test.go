@@ -0,0 +1,77 @@
+package pkghttp
+
+import (
+	"net"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testBindEmbedded struct {
+	Page uint16 `query:"page"`
+}
+
+type testBindRequest struct {
+	testBindEmbedded
+	ID       int64         `path:"id" required:"true"`
+	Name     string        `form:"name"`
+	Tags     []string      `query:"tag"`
+	Ratio    float32       `query:"ratio"`
+	Enabled  *bool         `query:"enabled"`
+	Since    time.Time     `query:"since" layout:"2006-01-02"`
+	Timeout  time.Duration `header:"X-Timeout"`
+	Limit    int           `query:"limit" header:"X-Limit"`
+	IP       net.IP        `query:"ip"`
+	Allowed  []net.IP      `query:"allow"`
+	Ignored  string
+	Untagged int `query:"-"`
+}
+
+func TestBind(t *testing.T) {
+	request := httptest.NewRequest(
+		"POST",
+		"/things/42?page=3&tag=a&tag=b&ratio=0.5&enabled=true&since=2016-01-02&ip=1.2.3.4&allow=10.0.0.1&allow=::1",
+		strings.NewReader(url.Values{"name": []string{"foo"}}.Encode()),
+	)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("X-Timeout", "5s")
+	request.Header.Set("X-Limit", "10")
+	request = WithPathValues(request, map[string]string{"id": "42"})
+	dst := &testBindRequest{Ignored: "ignored"}
+	require.NoError(t, Bind(request, dst))
+	require.Equal(t, uint16(3), dst.Page)
+	require.Equal(t, int64(42), dst.ID)
+	require.Equal(t, "foo", dst.Name)
+	require.Equal(t, []string{"a", "b"}, dst.Tags)
+	require.Equal(t, float32(0.5), dst.Ratio)
+	require.NotNil(t, dst.Enabled)
+	require.True(t, *dst.Enabled)
+	require.Equal(t, time.Date(2016, 1, 2, 0, 0, 0, 0, time.UTC), dst.Since)
+	require.Equal(t, 5*time.Second, dst.Timeout)
+	require.Equal(t, 10, dst.Limit)
+	require.Equal(t, net.ParseIP("1.2.3.4"), dst.IP)
+	require.Equal(t, []net.IP{net.ParseIP("10.0.0.1"), net.ParseIP("::1")}, dst.Allowed)
+	require.Equal(t, "ignored", dst.Ignored)
+}
+
+func TestBindError(t *testing.T) {
+	request := httptest.NewRequest("GET", "/things?page=-1&ratio=foo", nil)
+	dst := &testBindRequest{}
+	err := Bind(request, dst)
+	require.Error(t, err)
+	bindError, ok := err.(*BindError)
+	require.True(t, ok)
+	require.Len(t, bindError.FieldErrors, 3)
+	require.Equal(t, "Page", bindError.FieldErrors[0].Field)
+	require.Equal(t, "ID", bindError.FieldErrors[1].Field)
+	require.Equal(t, "path id: required", bindError.FieldErrors[1].Error())
+	require.Equal(t, "Ratio", bindError.FieldErrors[2].Field)
+	require.Equal(t, "query", bindError.FieldErrors[2].Source)
+	require.Equal(t, "foo", bindError.FieldErrors[2].Value)
+
+	require.Error(t, Bind(request, testBindRequest{}))
+}
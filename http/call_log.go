@@ -0,0 +1,193 @@
+package pkghttp
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"go.pedge.io/lion/proto"
+)
+
+const redactedValue = "REDACTED"
+
+var (
+	defaultLogRedactHeaders = []string{
+		"Authorization",
+		"Cookie",
+		"Proxy-Authorization",
+		"Set-Cookie",
+	}
+	defaultLogRedactFormKeys = []string{
+		"access_token",
+		"api_key",
+		"password",
+		"secret",
+		"token",
+	}
+)
+
+type callLogger struct {
+	redactHeaders  map[string]bool
+	redactFormKeys map[string]bool
+	skipPaths      []string
+	sampleRates    map[string]float64
+}
+
+func newCallLogMiddleware(opts CallLogOptions) Middleware {
+	return newCallLogger(opts).wrap
+}
+
+func newCallLogger(opts CallLogOptions) *callLogger {
+	c := &callLogger{
+		make(map[string]bool),
+		make(map[string]bool),
+		opts.SkipPaths,
+		opts.SampleRates,
+	}
+	// the defaults are always redacted, so that configuring another key does not
+	// stop redacting credentials
+	for _, headers := range [][]string{defaultLogRedactHeaders, opts.RedactHeaders} {
+		for _, header := range headers {
+			c.redactHeaders[http.CanonicalHeaderKey(header)] = true
+		}
+	}
+	for _, keys := range [][]string{defaultLogRedactFormKeys, opts.RedactFormKeys} {
+		for _, key := range keys {
+			c.redactFormKeys[strings.ToLower(key)] = true
+		}
+	}
+	return c
+}
+
+func (c *callLogger) wrap(handler http.Handler) http.Handler {
+	return http.HandlerFunc(
+		func(responseWriter http.ResponseWriter, request *http.Request) {
+			responseWriter, request, state := withCallState(responseWriter, request)
+			state.logged = true
+			defer func() {
+				call := state.call
+				if call.Method == "" {
+					call.Method = request.Method
+				}
+				if call.RequestHeader == nil {
+					call.RequestHeader = c.headerMap(request.Header)
+				}
+				if call.RequestForm == nil {
+					call.RequestForm = c.formMap(state.request.Form)
+				}
+				if call.ResponseHeader == nil {
+					call.ResponseHeader = c.headerMap(state.responseWriter.Header())
+				}
+				if request.URL != nil {
+					if call.Path == "" {
+						call.Path = request.URL.Path
+					}
+					if call.Query == nil {
+						call.Query = c.formMap(request.URL.Query())
+					}
+				}
+				state.finish()
+				if c.shouldLog(call) {
+					protolion.Info(call)
+				}
+			}()
+			handler.ServeHTTP(responseWriter, request)
+		},
+	)
+}
+
+func (c *callLogger) headerMap(header http.Header) map[string]string {
+	m := valuesMap(header)
+	for key := range m {
+		if c.redactHeaders[http.CanonicalHeaderKey(key)] {
+			m[key] = redactedValue
+		}
+	}
+	return m
+}
+
+func (c *callLogger) formMap(form map[string][]string) map[string]string {
+	m := valuesMap(form)
+	for key := range m {
+		if c.redactFormKeys[strings.ToLower(key)] {
+			m[key] = redactedValue
+		}
+	}
+	return m
+}
+
+func (c *callLogger) shouldLog(call *Call) bool {
+	if matchesPathPatterns(call.Path, c.skipPaths) {
+		return false
+	}
+	if call.Error != "" {
+		return true
+	}
+	rate, ok := c.sampleRates[strconv.Itoa(int(call.StatusCode))]
+	if !ok {
+		rate, ok = c.sampleRates[fmt.Sprintf("%dxx", call.StatusCode/100)]
+	}
+	if !ok || rate >= 1 {
+		return true
+	}
+	return rand.Float64() < rate
+}
+
+func newCallLogOptions(handlerEnv HandlerEnv) (CallLogOptions, error) {
+	opts := CallLogOptions{
+		RedactHeaders:  splitList(handlerEnv.LogRedactHeaders),
+		RedactFormKeys: splitList(handlerEnv.LogRedactFormKeys),
+		SkipPaths:      splitList(handlerEnv.LogSkipPaths),
+	}
+	for _, skipPath := range opts.SkipPaths {
+		if _, err := path.Match(skipPath, ""); err != nil {
+			return CallLogOptions{}, fmt.Errorf("pkghttp: invalid log skip path %s: %s", skipPath, err.Error())
+		}
+	}
+	sampleRates := splitList(handlerEnv.LogSampleRates)
+	if len(sampleRates) > 0 {
+		opts.SampleRates = make(map[string]float64)
+	}
+	for _, sampleRate := range sampleRates {
+		split := strings.SplitN(sampleRate, "=", 2)
+		if len(split) != 2 {
+			return CallLogOptions{}, fmt.Errorf("pkghttp: invalid log sample rate %s, must be status=rate", sampleRate)
+		}
+		rate, err := strconv.ParseFloat(strings.TrimSpace(split[1]), 64)
+		if err != nil || rate < 0 || rate > 1 {
+			return CallLogOptions{}, fmt.Errorf("pkghttp: invalid log sample rate %s, rate must be between 0 and 1", sampleRate)
+		}
+		opts.SampleRates[strings.ToLower(strings.TrimSpace(split[0]))] = rate
+	}
+	return opts, nil
+}
+
+// a pattern ending in /** matches every path under the prefix,
+// otherwise patterns are matched with path.Match
+func matchesPathPatterns(p string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if strings.HasSuffix(pattern, "/**") {
+			if strings.HasPrefix(p, strings.TrimSuffix(pattern, "**")) {
+				return true
+			}
+			continue
+		}
+		if matched, _ := path.Match(pattern, p); matched {
+			return true
+		}
+	}
+	return false
+}
+
+func splitList(s string) []string {
+	var list []string
+	for _, element := range strings.Split(s, ",") {
+		if element = strings.TrimSpace(element); element != "" {
+			list = append(list, element)
+		}
+	}
+	return list
+}
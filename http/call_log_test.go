@@ -0,0 +1,73 @@
+package pkghttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCallLogRedaction(t *testing.T) {
+	var call *Call
+	handler := NewChain(
+		NewCallLogMiddleware(
+			CallLogOptions{
+				RedactHeaders:  []string{"x-api-key"},
+				RedactFormKeys: []string{"Session"},
+			},
+		),
+	).Then(
+		http.HandlerFunc(
+			func(responseWriter http.ResponseWriter, request *http.Request) {
+				call = CallFromContext(request.Context())
+			},
+		),
+	)
+	request := httptest.NewRequest("GET", "/foo?password=hunter2&session=abc&user=bob", nil)
+	request.Header.Set("Authorization", "Bearer abc")
+	request.Header.Set("X-Api-Key", "abc")
+	request.Header.Set("Accept", "text/plain")
+	handler.ServeHTTP(httptest.NewRecorder(), request)
+	require.NotNil(t, call)
+	require.Equal(t, map[string]string{"password": "REDACTED", "session": "REDACTED", "user": "bob"}, call.Query)
+	require.Equal(t, "REDACTED", call.RequestHeader["Authorization"])
+	require.Equal(t, "REDACTED", call.RequestHeader["X-Api-Key"])
+	require.Equal(t, "text/plain", call.RequestHeader["Accept"])
+
+	// the defaults are redacted with no options
+	handler = NewCallLogMiddleware(CallLogOptions{})(
+		http.HandlerFunc(
+			func(responseWriter http.ResponseWriter, request *http.Request) {
+				call = CallFromContext(request.Context())
+			},
+		),
+	)
+	request = httptest.NewRequest("GET", "/foo?token=abc", nil)
+	request.Header.Set("Cookie", "session=abc")
+	handler.ServeHTTP(httptest.NewRecorder(), request)
+	require.Equal(t, map[string]string{"token": "REDACTED"}, call.Query)
+	require.Equal(t, "REDACTED", call.RequestHeader["Cookie"])
+}
+
+func TestCallLogShouldLog(t *testing.T) {
+	opts, err := NewCallLogOptions(
+		HandlerEnv{
+			LogSkipPaths:   "/static/**,/favicon.ico",
+			LogSampleRates: "2xx=0, 204=1",
+		},
+	)
+	require.NoError(t, err)
+	logger := &callLogger{nil, nil, opts.SkipPaths, opts.SampleRates}
+	require.False(t, logger.shouldLog(&Call{Path: "/static/js/app.js", StatusCode: 500, Error: "oops"}))
+	require.False(t, logger.shouldLog(&Call{Path: "/favicon.ico", StatusCode: 404}))
+	require.False(t, logger.shouldLog(&Call{Path: "/foo", StatusCode: 200}))
+	require.True(t, logger.shouldLog(&Call{Path: "/foo", StatusCode: 204}))
+	require.True(t, logger.shouldLog(&Call{Path: "/foo", StatusCode: 200, Error: "oops"}))
+	require.True(t, logger.shouldLog(&Call{Path: "/foo", StatusCode: 404}))
+
+	_, err = NewCallLogOptions(HandlerEnv{LogSampleRates: "2xx"})
+	require.Error(t, err)
+	_, err = NewCallLogOptions(HandlerEnv{LogSampleRates: "2xx=2"})
+	require.Error(t, err)
+}
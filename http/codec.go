@@ -0,0 +1,156 @@
+package pkghttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+)
+
+const contentTypeProtobufAlias = "application/protobuf"
+
+func decode(request *http.Request, dst interface{}, opts DecodeOptions) error {
+	maxBodyBytes := opts.MaxBodyBytes
+	if maxBodyBytes == 0 {
+		maxBodyBytes = DefaultMaxBodyBytes
+	}
+	contentType := ContentTypeJSON
+	if header := request.Header.Get("Content-Type"); header != "" {
+		mediaType, _, err := mime.ParseMediaType(header)
+		if err != nil {
+			return &DecodeError{http.StatusUnsupportedMediaType, fmt.Errorf("invalid Content-Type %s", header)}
+		}
+		contentType = mediaType
+	}
+	message, isMessage := dst.(proto.Message)
+	switch contentType {
+	case ContentTypeJSON:
+	case ContentTypeProtobuf, contentTypeProtobufAlias:
+		if !isMessage {
+			return &DecodeError{http.StatusUnsupportedMediaType, fmt.Errorf("Content-Type %s not supported", contentType)}
+		}
+	default:
+		return &DecodeError{http.StatusUnsupportedMediaType, fmt.Errorf("Content-Type %s not supported", contentType)}
+	}
+	if request.ContentLength > maxBodyBytes {
+		return &DecodeError{http.StatusRequestEntityTooLarge, fmt.Errorf("body larger than %d bytes", maxBodyBytes)}
+	}
+	if request.Body == nil {
+		return &DecodeError{http.StatusBadRequest, fmt.Errorf("empty body")}
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(request.Body, maxBodyBytes+1))
+	if err == ErrRequestBodyTooLarge {
+		return &DecodeError{http.StatusRequestEntityTooLarge, err}
+	}
+	if err != nil {
+		return &DecodeError{http.StatusBadRequest, err}
+	}
+	if int64(len(data)) > maxBodyBytes {
+		return &DecodeError{http.StatusRequestEntityTooLarge, fmt.Errorf("body larger than %d bytes", maxBodyBytes)}
+	}
+	if len(data) == 0 {
+		return &DecodeError{http.StatusBadRequest, fmt.Errorf("empty body")}
+	}
+	switch {
+	case contentType != ContentTypeJSON:
+		err = proto.Unmarshal(data, message)
+	case isMessage:
+		err = (&jsonpb.Unmarshaler{AllowUnknownFields: opts.AllowUnknownFields}).Unmarshal(bytes.NewReader(data), message)
+	default:
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		if !opts.AllowUnknownFields {
+			decoder.DisallowUnknownFields()
+		}
+		err = decoder.Decode(dst)
+	}
+	if err != nil {
+		return &DecodeError{http.StatusBadRequest, err}
+	}
+	return nil
+}
+
+func encode(responseWriter http.ResponseWriter, request *http.Request, statusCode int, src interface{}) error {
+	message, isMessage := src.(proto.Message)
+	contentType := negotiateContentType(request.Header.Get("Accept"), isMessage)
+	if contentType == "" {
+		err := &DecodeError{http.StatusNotAcceptable, fmt.Errorf("Accept %s not supported", request.Header.Get("Accept"))}
+		writeProblem(responseWriter, request, err)
+		return err
+	}
+	var data []byte
+	var err error
+	switch {
+	case contentType == ContentTypeProtobuf:
+		data, err = proto.Marshal(message)
+	case isMessage:
+		buffer := bytes.NewBuffer(nil)
+		err = (&jsonpb.Marshaler{}).Marshal(buffer, message)
+		data = buffer.Bytes()
+	default:
+		data, err = json.Marshal(src)
+	}
+	if err != nil {
+		writeProblem(responseWriter, request, err)
+		return err
+	}
+	responseWriter.Header().Set("Content-Type", contentType)
+	responseWriter.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	responseWriter.WriteHeader(statusCode)
+	_, err = responseWriter.Write(data)
+	return err
+}
+
+// negotiateContentType returns the content type to encode a response in for the
+// given Accept header, or "" if neither JSON nor protobuf is acceptable. Protobuf
+// is only acceptable if the response is a proto.Message, and JSON is preferred
+// if both are equally acceptable.
+func negotiateContentType(accept string, isMessage bool) string {
+	if accept == "" {
+		return ContentTypeJSON
+	}
+	qualities := make(map[string]float64)
+	for _, element := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(element))
+		if err != nil {
+			continue
+		}
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			if quality, err = strconv.ParseFloat(q, 64); err != nil {
+				quality = 0
+			}
+		}
+		if mediaType == contentTypeProtobufAlias {
+			mediaType = ContentTypeProtobuf
+		}
+		qualities[mediaType] = quality
+	}
+	candidates := []string{ContentTypeJSON}
+	if isMessage {
+		candidates = append(candidates, ContentTypeProtobuf)
+	}
+	bestContentType := ""
+	bestQuality := 0.0
+	for _, contentType := range candidates {
+		quality, ok := qualities[contentType]
+		if !ok {
+			quality, ok = qualities["application/*"]
+		}
+		if !ok {
+			quality = qualities["*/*"]
+		}
+		if quality > bestQuality {
+			bestContentType = contentType
+			bestQuality = quality
+		}
+	}
+	return bestContentType
+}
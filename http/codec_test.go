@@ -0,0 +1,66 @@
+package pkghttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testCodecBody struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+func TestDecode(t *testing.T) {
+	newRequest := func(contentType string, body string) *http.Request {
+		request := httptest.NewRequest("POST", "/", strings.NewReader(body))
+		if contentType != "" {
+			request.Header.Set("Content-Type", contentType)
+		}
+		return request
+	}
+	dst := &testCodecBody{}
+	require.NoError(t, Decode(newRequest("application/json; charset=utf-8", `{"name":"foo","count":2}`), dst))
+	require.Equal(t, &testCodecBody{"foo", 2}, dst)
+	require.NoError(t, Decode(newRequest("", `{"name":"bar"}`), dst))
+	require.Equal(t, "bar", dst.Name)
+
+	requireDecodeError := func(statusCode int, err error) {
+		require.Error(t, err)
+		decodeError, ok := err.(*DecodeError)
+		require.True(t, ok)
+		require.Equal(t, statusCode, decodeError.StatusCode)
+	}
+	requireDecodeError(http.StatusBadRequest, Decode(newRequest("", `{"foo":"bar"}`), dst))
+	require.NoError(t, DecodeWithOptions(newRequest("", `{"foo":"bar"}`), dst, DecodeOptions{AllowUnknownFields: true}))
+	requireDecodeError(http.StatusBadRequest, Decode(newRequest("", ``), dst))
+	requireDecodeError(http.StatusUnsupportedMediaType, Decode(newRequest("text/plain", `foo`), dst))
+	requireDecodeError(http.StatusUnsupportedMediaType, Decode(newRequest(ContentTypeProtobuf, `foo`), dst))
+	requireDecodeError(
+		http.StatusRequestEntityTooLarge,
+		DecodeWithOptions(newRequest("", `{"name":"foo"}`), dst, DecodeOptions{MaxBodyBytes: 4}),
+	)
+}
+
+func TestEncode(t *testing.T) {
+	request := httptest.NewRequest("GET", "/", nil)
+	request.Header.Set("Accept", "text/html, application/*;q=0.8")
+	recorder := httptest.NewRecorder()
+	require.NoError(t, Encode(recorder, request, http.StatusCreated, &testCodecBody{"foo", 2}))
+	require.Equal(t, http.StatusCreated, recorder.Code)
+	require.Equal(t, ContentTypeJSON, recorder.Header().Get("Content-Type"))
+	require.Equal(t, `{"name":"foo","count":2}`, recorder.Body.String())
+
+	request.Header.Set("Accept", ContentTypeProtobuf)
+	recorder = httptest.NewRecorder()
+	require.Error(t, Encode(recorder, request, http.StatusOK, &testCodecBody{"foo", 2}))
+	require.Equal(t, http.StatusNotAcceptable, recorder.Code)
+	require.Equal(t, ContentTypeProblemJSON, recorder.Header().Get("Content-Type"))
+	require.Contains(t, recorder.Body.String(), `"detail":"Accept application/x-protobuf not supported"`)
+
+	require.Equal(t, ContentTypeProtobuf, negotiateContentType("application/json;q=0.5, application/protobuf", true))
+	require.Equal(t, ContentTypeJSON, negotiateContentType("*/*", true))
+}
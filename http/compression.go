@@ -0,0 +1,298 @@
+package pkghttp
+
+import (
+	"bufio"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	encodingGzip    = "gzip"
+	encodingDeflate = "deflate"
+)
+
+var defaultCompressionContentTypes = []string{
+	"text/*",
+	"application/javascript",
+	"application/json",
+	"application/problem+json",
+	"application/x-javascript",
+	"application/xml",
+	"image/svg+xml",
+}
+
+type compressWriter interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+type compression struct {
+	minSize      int
+	contentTypes []string
+	pools        map[string]*sync.Pool
+}
+
+func newCompressionMiddleware(opts CompressionOptions) Middleware {
+	level := opts.Level
+	if level == 0 || level < gzip.HuffmanOnly || level > gzip.BestCompression {
+		level = gzip.DefaultCompression
+	}
+	minSize := opts.MinSize
+	if minSize == 0 {
+		minSize = 1024
+	}
+	contentTypes := opts.ContentTypes
+	if len(contentTypes) == 0 {
+		contentTypes = defaultCompressionContentTypes
+	}
+	c := &compression{
+		minSize,
+		contentTypes,
+		map[string]*sync.Pool{
+			encodingGzip: {
+				New: func() interface{} {
+					// the level is validated above
+					w, _ := gzip.NewWriterLevel(nil, level)
+					return w
+				},
+			},
+			encodingDeflate: {
+				New: func() interface{} {
+					// the level is validated above
+					w, _ := zlib.NewWriterLevel(nil, level)
+					return w
+				},
+			},
+		},
+	}
+	return c.wrap
+}
+
+func (c *compression) wrap(handler http.Handler) http.Handler {
+	return http.HandlerFunc(
+		func(responseWriter http.ResponseWriter, request *http.Request) {
+			responseWriter.Header().Add("Vary", "Accept-Encoding")
+			encoding := negotiateEncoding(request.Header.Get("Accept-Encoding"))
+			// the ranges of a compressed response would not match the ranges requested
+			if encoding == "" || request.Header.Get("Range") != "" {
+				handler.ServeHTTP(responseWriter, request)
+				return
+			}
+			compressResponseWriter := &compressResponseWriter{
+				compression:    c,
+				responseWriter: responseWriter,
+				encoding:       encoding,
+			}
+			defer compressResponseWriter.close()
+			handler.ServeHTTP(
+				withOptionalInterfaces(
+					compressResponseWriter,
+					responseWriter,
+					compressFlusher{compressResponseWriter},
+					compressHijacker{compressResponseWriter},
+				),
+				request,
+			)
+		},
+	)
+}
+
+func (c *compression) isCompressible(contentType string) bool {
+	if i := strings.IndexByte(contentType, ';'); i >= 0 {
+		contentType = contentType[:i]
+	}
+	contentType = strings.ToLower(strings.TrimSpace(contentType))
+	for _, allowed := range c.contentTypes {
+		if strings.HasSuffix(allowed, "/*") {
+			if strings.HasPrefix(contentType, strings.TrimSuffix(allowed, "*")) {
+				return true
+			}
+		} else if contentType == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// compressResponseWriter buffers the response until it is known whether the
+// response should be compressed, which is when minSize bytes have been written,
+// the response is flushed, or the handler returns.
+type compressResponseWriter struct {
+	compression    *compression
+	responseWriter http.ResponseWriter
+	encoding       string
+	statusCode     int
+	buffer         []byte
+	started        bool
+	hijacked       bool
+	writer         compressWriter
+}
+
+func (w *compressResponseWriter) Header() http.Header {
+	return w.responseWriter.Header()
+}
+
+func (w *compressResponseWriter) WriteHeader(statusCode int) {
+	if w.started || w.statusCode != 0 {
+		return
+	}
+	if statusCode >= 100 && statusCode < 200 && statusCode != http.StatusSwitchingProtocols {
+		w.responseWriter.WriteHeader(statusCode)
+		return
+	}
+	w.statusCode = statusCode
+	if !bodyAllowed(statusCode) {
+		_ = w.start(false)
+	}
+}
+
+func (w *compressResponseWriter) Write(p []byte) (int, error) {
+	if w.started {
+		if w.writer != nil {
+			return w.writer.Write(p)
+		}
+		return w.responseWriter.Write(p)
+	}
+	w.buffer = append(w.buffer, p...)
+	if len(w.buffer) >= w.compression.minSize {
+		if err := w.start(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+func (w *compressResponseWriter) flush() {
+	if !w.started {
+		// a flushed response is compressed regardless of size, as more is likely to follow
+		_ = w.start(true)
+	}
+	if w.writer != nil {
+		_ = w.writer.Flush()
+	}
+	if flusher, ok := w.responseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *compressResponseWriter) hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, readWriter, err := w.responseWriter.(http.Hijacker).Hijack()
+	if err == nil {
+		w.hijacked = true
+	}
+	return conn, readWriter, err
+}
+
+func (w *compressResponseWriter) close() {
+	if w.hijacked {
+		return
+	}
+	if !w.started {
+		if w.statusCode == 0 && len(w.buffer) == 0 {
+			// nothing was written, leave the response to the server
+			return
+		}
+		_ = w.start(len(w.buffer) >= w.compression.minSize)
+	}
+	if w.writer != nil {
+		_ = w.writer.Close()
+		w.compression.pools[w.encoding].Put(w.writer)
+		w.writer = nil
+	}
+}
+
+func (w *compressResponseWriter) start(compress bool) error {
+	w.started = true
+	header := w.Header()
+	if w.statusCode == 0 {
+		w.statusCode = http.StatusOK
+	}
+	if header.Get("Content-Type") == "" && len(w.buffer) > 0 {
+		header.Set("Content-Type", http.DetectContentType(w.buffer))
+	}
+	if compress &&
+		bodyAllowed(w.statusCode) &&
+		header.Get("Content-Encoding") == "" &&
+		w.compression.isCompressible(header.Get("Content-Type")) {
+		header.Del("Content-Length")
+		header.Set("Content-Encoding", w.encoding)
+		w.writer = w.compression.pools[w.encoding].Get().(compressWriter)
+		w.writer.Reset(w.responseWriter)
+	}
+	w.responseWriter.WriteHeader(w.statusCode)
+	buffer := w.buffer
+	w.buffer = nil
+	if len(buffer) == 0 {
+		return nil
+	}
+	_, err := w.Write(buffer)
+	return err
+}
+
+type compressFlusher struct {
+	w *compressResponseWriter
+}
+
+func (f compressFlusher) Flush() {
+	f.w.flush()
+}
+
+type compressHijacker struct {
+	w *compressResponseWriter
+}
+
+func (h compressHijacker) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return h.w.hijack()
+}
+
+// negotiateEncoding returns the encoding to use for the given Accept-Encoding
+// header, or "" if the response should not be compressed. gzip is preferred
+// over deflate if both are equally acceptable.
+func negotiateEncoding(acceptEncoding string) string {
+	if acceptEncoding == "" {
+		return ""
+	}
+	qualities := make(map[string]float64)
+	for _, element := range strings.Split(acceptEncoding, ",") {
+		params := strings.Split(element, ";")
+		name := strings.ToLower(strings.TrimSpace(params[0]))
+		quality := 1.0
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				parsed, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64)
+				if err != nil {
+					parsed = 0
+				}
+				quality = parsed
+			}
+		}
+		qualities[name] = quality
+	}
+	bestEncoding := ""
+	bestQuality := 0.0
+	for _, encoding := range []string{encodingGzip, encodingDeflate} {
+		quality, ok := qualities[encoding]
+		if !ok {
+			quality = qualities["*"]
+		}
+		if quality > bestQuality {
+			bestEncoding = encoding
+			bestQuality = quality
+		}
+	}
+	return bestEncoding
+}
+
+func bodyAllowed(statusCode int) bool {
+	return statusCode != http.StatusNoContent &&
+		statusCode != http.StatusNotModified &&
+		(statusCode < 100 || statusCode >= 200)
+}
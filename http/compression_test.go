@@ -0,0 +1,104 @@
+package pkghttp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompression(t *testing.T) {
+	body := strings.Repeat(`{"foo":"bar"}`, 100)
+	var flusher bool
+	var hijacker bool
+	handler := NewCompressionMiddleware(CompressionOptions{})(
+		http.HandlerFunc(
+			func(responseWriter http.ResponseWriter, request *http.Request) {
+				_, flusher = responseWriter.(http.Flusher)
+				_, hijacker = responseWriter.(http.Hijacker)
+				switch request.URL.Path {
+				case "/small":
+					responseWriter.Header().Set("Content-Type", "application/json")
+					_, _ = responseWriter.Write([]byte(`{}`))
+				case "/image":
+					responseWriter.Header().Set("Content-Type", "image/png")
+					_, _ = responseWriter.Write([]byte(body))
+				default:
+					responseWriter.Header().Set("Content-Type", "application/json")
+					responseWriter.Header().Set("Content-Length", "1300")
+					_, _ = responseWriter.Write([]byte(body[:100]))
+					_, _ = responseWriter.Write([]byte(body[100:]))
+				}
+			},
+		),
+	)
+	serve := func(path string, acceptEncoding string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest("GET", path, nil)
+		request.Header.Set("Accept-Encoding", acceptEncoding)
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	recorder := serve("/", "deflate, gzip")
+	require.True(t, flusher)
+	require.False(t, hijacker)
+	require.Equal(t, "gzip", recorder.Header().Get("Content-Encoding"))
+	require.Equal(t, "", recorder.Header().Get("Content-Length"))
+	require.Equal(t, "Accept-Encoding", recorder.Header().Get("Vary"))
+	reader, err := gzip.NewReader(recorder.Body)
+	require.NoError(t, err)
+	data, err := ioutil.ReadAll(reader)
+	require.NoError(t, err)
+	require.Equal(t, body, string(data))
+
+	recorder = serve("/", "gzip;q=0.5, deflate")
+	require.Equal(t, "deflate", recorder.Header().Get("Content-Encoding"))
+	reader2, err := zlib.NewReader(recorder.Body)
+	require.NoError(t, err)
+	data, err = ioutil.ReadAll(reader2)
+	require.NoError(t, err)
+	require.Equal(t, body, string(data))
+
+	recorder = serve("/", "gzip;q=0, identity")
+	require.Equal(t, "", recorder.Header().Get("Content-Encoding"))
+	require.Equal(t, body, recorder.Body.String())
+
+	recorder = serve("/small", "gzip")
+	require.Equal(t, "", recorder.Header().Get("Content-Encoding"))
+	require.Equal(t, "{}", recorder.Body.String())
+
+	recorder = serve("/image", "gzip")
+	require.Equal(t, "", recorder.Header().Get("Content-Encoding"))
+	require.Equal(t, body, recorder.Body.String())
+}
+
+func TestCompressionFlush(t *testing.T) {
+	handler := NewCompressionMiddleware(CompressionOptions{})(
+		http.HandlerFunc(
+			func(responseWriter http.ResponseWriter, request *http.Request) {
+				responseWriter.Header().Set("Content-Type", "text/plain")
+				_, _ = responseWriter.Write([]byte("hello"))
+				responseWriter.(http.Flusher).Flush()
+				_, _ = responseWriter.Write([]byte(" world"))
+			},
+		),
+	)
+	request := httptest.NewRequest("GET", "/", nil)
+	request.Header.Set("Accept-Encoding", "gzip")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	require.True(t, recorder.Flushed)
+	require.Equal(t, "gzip", recorder.Header().Get("Content-Encoding"))
+	reader, err := gzip.NewReader(bytes.NewReader(recorder.Body.Bytes()))
+	require.NoError(t, err)
+	data, err := ioutil.ReadAll(reader)
+	require.NoError(t, err)
+	require.Equal(t, "hello world", string(data))
+}
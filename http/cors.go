@@ -0,0 +1,213 @@
+package pkghttp
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	defaultCORSAllowedMethods = []string{
+		"GET",
+		"HEAD",
+		"POST",
+	}
+	defaultCORSAllowedHeaders = []string{
+		"Accept",
+		"Accept-Language",
+		"Content-Language",
+		"Content-Type",
+		RequestIDHeader,
+	}
+)
+
+type cors struct {
+	allowAllOrigins  bool
+	allowedOrigins   map[string]bool
+	wildcardOrigins  [][2]string
+	allowedMethods   map[string]bool
+	allowAllHeaders  bool
+	allowedHeaders   map[string]bool
+	methods          string
+	exposedHeaders   string
+	allowCredentials bool
+	maxAge           string
+}
+
+func newCORSMiddleware(opts CORSOptions) (Middleware, error) {
+	if err := validateCORSOptions(opts); err != nil {
+		return nil, err
+	}
+	c := &cors{
+		allowedOrigins:   make(map[string]bool),
+		allowedMethods:   make(map[string]bool),
+		allowedHeaders:   make(map[string]bool),
+		exposedHeaders:   strings.Join(opts.ExposedHeaders, ", "),
+		allowCredentials: opts.AllowCredentials,
+	}
+	for _, origin := range opts.AllowedOrigins {
+		origin = strings.ToLower(origin)
+		if origin == "*" {
+			c.allowAllOrigins = true
+		} else if i := strings.IndexByte(origin, '*'); i >= 0 {
+			c.wildcardOrigins = append(c.wildcardOrigins, [2]string{origin[:i], origin[i+1:]})
+		} else {
+			c.allowedOrigins[origin] = true
+		}
+	}
+	allowedMethods := opts.AllowedMethods
+	if len(allowedMethods) == 0 {
+		allowedMethods = defaultCORSAllowedMethods
+	}
+	methods := make([]string, len(allowedMethods))
+	for i, method := range allowedMethods {
+		methods[i] = strings.ToUpper(method)
+		c.allowedMethods[methods[i]] = true
+	}
+	c.methods = strings.Join(methods, ", ")
+	allowedHeaders := opts.AllowedHeaders
+	if len(allowedHeaders) == 0 {
+		allowedHeaders = defaultCORSAllowedHeaders
+	}
+	for _, header := range allowedHeaders {
+		if header == "*" {
+			c.allowAllHeaders = true
+		}
+		c.allowedHeaders[http.CanonicalHeaderKey(header)] = true
+	}
+	if opts.MaxAge > 0 {
+		c.maxAge = strconv.Itoa(int(opts.MaxAge.Seconds()))
+	}
+	return c.wrap, nil
+}
+
+func (c *cors) wrap(handler http.Handler) http.Handler {
+	return http.HandlerFunc(
+		func(responseWriter http.ResponseWriter, request *http.Request) {
+			// whether and how the CORS headers are set depends on the origin, so a cache
+			// must not serve a response to a request without an origin for one with an origin
+			header := responseWriter.Header()
+			header.Add("Vary", "Origin")
+			origin := request.Header.Get("Origin")
+			if origin == "" {
+				handler.ServeHTTP(responseWriter, request)
+				return
+			}
+			if request.Method == "OPTIONS" && request.Header.Get("Access-Control-Request-Method") != "" {
+				header.Add("Vary", "Access-Control-Request-Method")
+				header.Add("Vary", "Access-Control-Request-Headers")
+				if rejection := c.preflightRejection(request, origin); rejection != "" {
+					if call := getCall(request.Context()); call != nil {
+						call.CorsRejection = rejection
+					}
+					http.Error(responseWriter, rejection, http.StatusForbidden)
+					return
+				}
+				c.setAllowOrigin(header, origin)
+				header.Set("Access-Control-Allow-Methods", c.methods)
+				if requestHeaders := request.Header.Get("Access-Control-Request-Headers"); requestHeaders != "" {
+					header.Set("Access-Control-Allow-Headers", requestHeaders)
+				}
+				if c.maxAge != "" {
+					header.Set("Access-Control-Max-Age", c.maxAge)
+				}
+				responseWriter.WriteHeader(http.StatusNoContent)
+				return
+			}
+			if !c.isAllowedOrigin(origin) {
+				// the request is still served, the browser does not expose
+				// the response to the origin without the CORS headers
+				if call := getCall(request.Context()); call != nil {
+					call.CorsRejection = fmt.Sprintf("origin %s not allowed", origin)
+				}
+				handler.ServeHTTP(responseWriter, request)
+				return
+			}
+			c.setAllowOrigin(header, origin)
+			if c.exposedHeaders != "" {
+				header.Set("Access-Control-Expose-Headers", c.exposedHeaders)
+			}
+			handler.ServeHTTP(responseWriter, request)
+		},
+	)
+}
+
+func (c *cors) preflightRejection(request *http.Request, origin string) string {
+	if !c.isAllowedOrigin(origin) {
+		return fmt.Sprintf("origin %s not allowed", origin)
+	}
+	method := strings.ToUpper(request.Header.Get("Access-Control-Request-Method"))
+	if !c.allowedMethods[method] {
+		return fmt.Sprintf("method %s not allowed", method)
+	}
+	if c.allowAllHeaders {
+		return ""
+	}
+	for _, requestHeader := range strings.Split(request.Header.Get("Access-Control-Request-Headers"), ",") {
+		requestHeader = http.CanonicalHeaderKey(strings.TrimSpace(requestHeader))
+		if requestHeader != "" && !c.allowedHeaders[requestHeader] {
+			return fmt.Sprintf("header %s not allowed", requestHeader)
+		}
+	}
+	return ""
+}
+
+func (c *cors) isAllowedOrigin(origin string) bool {
+	if c.allowAllOrigins {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	if c.allowedOrigins[origin] {
+		return true
+	}
+	for _, wildcardOrigin := range c.wildcardOrigins {
+		if len(origin) > len(wildcardOrigin[0])+len(wildcardOrigin[1]) &&
+			strings.HasPrefix(origin, wildcardOrigin[0]) &&
+			strings.HasSuffix(origin, wildcardOrigin[1]) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *cors) setAllowOrigin(header http.Header, origin string) {
+	if c.allowAllOrigins {
+		header.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		header.Set("Access-Control-Allow-Origin", origin)
+	}
+	if c.allowCredentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+func newCORSOptions(handlerEnv HandlerEnv) (CORSOptions, error) {
+	opts := CORSOptions{
+		AllowedOrigins:   splitList(handlerEnv.CORSAllowedOrigins),
+		AllowedMethods:   splitList(handlerEnv.CORSAllowedMethods),
+		AllowedHeaders:   splitList(handlerEnv.CORSAllowedHeaders),
+		ExposedHeaders:   splitList(handlerEnv.CORSExposedHeaders),
+		AllowCredentials: handlerEnv.CORSAllowCredentials,
+		MaxAge:           time.Duration(handlerEnv.CORSMaxAgeSec) * time.Second,
+	}
+	if err := validateCORSOptions(opts); err != nil {
+		return CORSOptions{}, err
+	}
+	return opts, nil
+}
+
+// validateCORSOptions rejects allowing all origins with credentials, as the
+// origin of every request would then be allowed to make credentialed requests.
+func validateCORSOptions(opts CORSOptions) error {
+	for _, origin := range opts.AllowedOrigins {
+		if origin == "*" && opts.AllowCredentials {
+			return fmt.Errorf("pkghttp: cannot allow all CORS origins with credentials")
+		}
+		if strings.Count(origin, "*") > 1 || (origin != "*" && strings.Contains(origin, "*") && !strings.Contains(origin, "*.")) {
+			return fmt.Errorf("pkghttp: invalid CORS origin %s, a wildcard must be a single subdomain such as https://*.example.com", origin)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package pkghttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCORS(t *testing.T) {
+	var call *Call
+	corsMiddleware, err := NewCORSMiddleware(
+		CORSOptions{
+			AllowedOrigins:   []string{"https://example.com", "https://*.example.org"},
+			AllowedMethods:   []string{"get", "put"},
+			AllowCredentials: true,
+			MaxAge:           time.Hour,
+		},
+	)
+	require.NoError(t, err)
+	handler := NewChain(
+		NewCallLogMiddleware(CallLogOptions{}),
+		func(handler http.Handler) http.Handler {
+			return http.HandlerFunc(
+				func(responseWriter http.ResponseWriter, request *http.Request) {
+					call = CallFromContext(request.Context())
+					handler.ServeHTTP(responseWriter, request)
+				},
+			)
+		},
+		corsMiddleware,
+	).Then(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	serve := func(method string, origin string, requestMethod string, requestHeaders string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(method, "/foo", nil)
+		request.Header.Set("Origin", origin)
+		if requestMethod != "" {
+			request.Header.Set("Access-Control-Request-Method", requestMethod)
+		}
+		if requestHeaders != "" {
+			request.Header.Set("Access-Control-Request-Headers", requestHeaders)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	recorder := serve("OPTIONS", "https://api.example.org", "PUT", "content-type, x-request-id")
+	require.Equal(t, http.StatusNoContent, recorder.Code)
+	require.Equal(t, "https://api.example.org", recorder.Header().Get("Access-Control-Allow-Origin"))
+	require.Equal(t, "GET, PUT", recorder.Header().Get("Access-Control-Allow-Methods"))
+	require.Equal(t, "content-type, x-request-id", recorder.Header().Get("Access-Control-Allow-Headers"))
+	require.Equal(t, "true", recorder.Header().Get("Access-Control-Allow-Credentials"))
+	require.Equal(t, "3600", recorder.Header().Get("Access-Control-Max-Age"))
+	require.Equal(t, []string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"}, recorder.Header()["Vary"])
+	require.Equal(t, "", call.CorsRejection)
+
+	recorder = serve("OPTIONS", "https://example.com", "DELETE", "")
+	require.Equal(t, http.StatusForbidden, recorder.Code)
+	require.Equal(t, "method DELETE not allowed", call.CorsRejection)
+
+	recorder = serve("OPTIONS", "https://example.com", "GET", "X-Foo")
+	require.Equal(t, http.StatusForbidden, recorder.Code)
+	require.Equal(t, "header X-Foo not allowed", call.CorsRejection)
+
+	recorder = serve("GET", "https://example.com", "", "")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, "https://example.com", recorder.Header().Get("Access-Control-Allow-Origin"))
+	require.Equal(t, "", call.CorsRejection)
+
+	recorder = serve("GET", "https://example.org", "", "")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, "", recorder.Header().Get("Access-Control-Allow-Origin"))
+	require.Equal(t, "origin https://example.org not allowed", call.CorsRejection)
+	require.Equal(t, []string{"Origin"}, recorder.Header()["Vary"])
+
+	// a response without an origin varies on the origin as well, so that it is
+	// not served from a cache to a request with an origin
+	recorder = serve("GET", "", "", "")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, "", recorder.Header().Get("Access-Control-Allow-Origin"))
+	require.Equal(t, []string{"Origin"}, recorder.Header()["Vary"])
+
+	_, err = NewCORSOptions(HandlerEnv{CORSAllowedOrigins: "*", CORSAllowCredentials: true})
+	require.Error(t, err)
+	_, err = NewCORSOptions(HandlerEnv{CORSAllowedOrigins: "https://*example.com"})
+	require.Error(t, err)
+	_, err = NewCORSMiddleware(CORSOptions{AllowedOrigins: []string{"https://a.com", "*"}, AllowCredentials: true})
+	require.Error(t, err)
+	_, err = NewCORSMiddleware(CORSOptions{AllowedOrigins: []string{"https://*.*.example.com"}})
+	require.Error(t, err)
+	opts, err := NewCORSOptions(HandlerEnv{CORSAllowedOrigins: "https://a.com, https://*.b.com", CORSMaxAgeSec: 60})
+	require.NoError(t, err)
+	require.Equal(t, []string{"https://a.com", "https://*.b.com"}, opts.AllowedOrigins)
+	require.Equal(t, time.Minute, opts.MaxAge)
+}
+
+func TestCORSAllowAllOrigins(t *testing.T) {
+	corsMiddleware, err := NewCORSMiddleware(CORSOptions{AllowedOrigins: []string{"*"}})
+	require.NoError(t, err)
+	handler := corsMiddleware(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	request := httptest.NewRequest("GET", "/foo", nil)
+	request.Header.Set("Origin", "https://evil.example.com")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	require.Equal(t, "*", recorder.Header().Get("Access-Control-Allow-Origin"))
+	require.Equal(t, "", recorder.Header().Get("Access-Control-Allow-Credentials"))
+}
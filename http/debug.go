@@ -0,0 +1,223 @@
+package pkghttp
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"runtime/pprof"
+	"runtime/trace"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.pedge.io/lion"
+)
+
+// net/http/pprof and expvar are not imported, as their init functions register
+// their handlers on http.DefaultServeMux, which is often served publicly.
+// As a result, /debug/vars serves the variables of DebugOptions.Vars, and not
+// the variables published with expvar.
+
+const (
+	debugPprofPrefix       = "/debug/pprof/"
+	defaultProfileDuration = 30 * time.Second
+)
+
+type buildInfo struct {
+	GoVersion string          `json:"go_version"`
+	Path      string          `json:"path,omitempty"`
+	Main      *debug.Module   `json:"main,omitempty"`
+	Deps      []*debug.Module `json:"deps,omitempty"`
+}
+
+func newDebugHandler(opts DebugOptions) http.Handler {
+	serveMux := http.NewServeMux()
+	serveMux.HandleFunc(debugPprofPrefix, handlePprof)
+	serveMux.HandleFunc(debugPprofPrefix+"cmdline", handleCmdline)
+	serveMux.HandleFunc(debugPprofPrefix+"profile", handleCPUProfile)
+	serveMux.HandleFunc(debugPprofPrefix+"trace", handleTrace)
+	serveMux.HandleFunc(
+		"/debug/vars",
+		func(responseWriter http.ResponseWriter, request *http.Request) {
+			handleVars(responseWriter, request, opts.Vars)
+		},
+	)
+	serveMux.HandleFunc("/debug/buildinfo", handleBuildInfo)
+	serveMux.HandleFunc("/debug/loglevel", handleLogLevel)
+	serveMux.HandleFunc("/debug/goroutines", handleGoroutines)
+	return serveMux
+}
+
+// handlePprof serves the index of the profiles on the prefix, and the profile
+// with the name of the rest of the path otherwise.
+func handlePprof(responseWriter http.ResponseWriter, request *http.Request) {
+	name := strings.TrimPrefix(request.URL.Path, debugPprofPrefix)
+	if name == "" {
+		handlePprofIndex(responseWriter, request)
+		return
+	}
+	profile := pprof.Lookup(name)
+	if profile == nil {
+		http.Error(responseWriter, fmt.Sprintf("unknown profile %s", name), http.StatusNotFound)
+		return
+	}
+	debugLevel, _ := strconv.Atoi(request.FormValue("debug"))
+	if name == "heap" && request.FormValue("gc") != "" {
+		runtime.GC()
+	}
+	if debugLevel != 0 {
+		responseWriter.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	} else {
+		setAttachmentHeaders(responseWriter, name)
+	}
+	_ = profile.WriteTo(responseWriter, debugLevel)
+}
+
+func handlePprofIndex(responseWriter http.ResponseWriter, request *http.Request) {
+	profiles := pprof.Profiles()
+	sort.Slice(profiles, func(i int, j int) bool { return profiles[i].Name() < profiles[j].Name() })
+	responseWriter.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = fmt.Fprintln(responseWriter, "<html><head><title>/debug/pprof/</title></head><body><table>")
+	for _, profile := range profiles {
+		name := html.EscapeString(profile.Name())
+		_, _ = fmt.Fprintf(responseWriter, "<tr><td>%d</td><td><a href=\"%s?debug=1\">%s</a></td></tr>\n", profile.Count(), name, name)
+	}
+	_, _ = fmt.Fprintln(responseWriter, "<tr><td></td><td><a href=\"profile\">profile</a></td></tr>")
+	_, _ = fmt.Fprintln(responseWriter, "<tr><td></td><td><a href=\"trace\">trace</a></td></tr>")
+	_, _ = fmt.Fprintln(responseWriter, "</table></body></html>")
+}
+
+func handleCmdline(responseWriter http.ResponseWriter, request *http.Request) {
+	responseWriter.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = fmt.Fprint(responseWriter, strings.Join(os.Args, "\x00"))
+}
+
+func handleCPUProfile(responseWriter http.ResponseWriter, request *http.Request) {
+	duration, err := getProfileDuration(request)
+	if err != nil {
+		http.Error(responseWriter, err.Error(), http.StatusBadRequest)
+		return
+	}
+	setAttachmentHeaders(responseWriter, "profile")
+	if err := pprof.StartCPUProfile(responseWriter); err != nil {
+		responseWriter.Header().Del("Content-Disposition")
+		http.Error(responseWriter, fmt.Sprintf("could not enable CPU profiling: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+	sleepForRequest(request, duration)
+	pprof.StopCPUProfile()
+}
+
+func handleTrace(responseWriter http.ResponseWriter, request *http.Request) {
+	duration, err := getProfileDuration(request)
+	if err != nil {
+		http.Error(responseWriter, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if request.FormValue("seconds") == "" {
+		duration = time.Second
+	}
+	setAttachmentHeaders(responseWriter, "trace")
+	if err := trace.Start(responseWriter); err != nil {
+		responseWriter.Header().Del("Content-Disposition")
+		http.Error(responseWriter, fmt.Sprintf("could not enable tracing: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+	sleepForRequest(request, duration)
+	trace.Stop()
+}
+
+func handleVars(responseWriter http.ResponseWriter, request *http.Request, getVars func() map[string]interface{}) {
+	vars := make(map[string]interface{})
+	if getVars != nil {
+		for key, value := range getVars() {
+			vars[key] = value
+		}
+	}
+	memStats := &runtime.MemStats{}
+	runtime.ReadMemStats(memStats)
+	vars["cmdline"] = os.Args
+	vars["memstats"] = memStats
+	data, err := json.MarshalIndent(vars, "", "  ")
+	if err != nil {
+		http.Error(responseWriter, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	responseWriter.Header().Set("Content-Type", ContentTypeJSON)
+	_, _ = responseWriter.Write(data)
+}
+
+func handleBuildInfo(responseWriter http.ResponseWriter, request *http.Request) {
+	info := &buildInfo{
+		GoVersion: runtime.Version(),
+	}
+	if readBuildInfo, ok := debug.ReadBuildInfo(); ok {
+		info.Path = readBuildInfo.Path
+		info.Main = &readBuildInfo.Main
+		info.Deps = readBuildInfo.Deps
+	}
+	data, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		http.Error(responseWriter, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	responseWriter.Header().Set("Content-Type", ContentTypeJSON)
+	_, _ = responseWriter.Write(data)
+}
+
+func handleLogLevel(responseWriter http.ResponseWriter, request *http.Request) {
+	switch request.Method {
+	case "GET", "HEAD":
+	case "PUT", "POST":
+		levelName := request.FormValue("level")
+		level, err := lion.NameToLevel(strings.ToUpper(levelName))
+		if err != nil {
+			http.Error(responseWriter, fmt.Sprintf("invalid level %s", levelName), http.StatusBadRequest)
+			return
+		}
+		lion.SetLevel(level)
+	default:
+		responseWriter.Header().Set("Allow", "GET, HEAD, PUT, POST")
+		http.Error(responseWriter, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	responseWriter.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = fmt.Fprintln(responseWriter, lion.GlobalLogger().Level().String())
+}
+
+func handleGoroutines(responseWriter http.ResponseWriter, request *http.Request) {
+	responseWriter.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_ = pprof.Lookup("goroutine").WriteTo(responseWriter, 2)
+}
+
+func getProfileDuration(request *http.Request) (time.Duration, error) {
+	secondsString := request.FormValue("seconds")
+	if secondsString == "" {
+		return defaultProfileDuration, nil
+	}
+	seconds, err := strconv.ParseFloat(secondsString, 64)
+	if err != nil || seconds <= 0 {
+		return 0, fmt.Errorf("invalid seconds %s", secondsString)
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// sleepForRequest sleeps for duration, or until the client goes away.
+func sleepForRequest(request *http.Request, duration time.Duration) {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-request.Context().Done():
+	}
+}
+
+func setAttachmentHeaders(responseWriter http.ResponseWriter, name string) {
+	responseWriter.Header().Set("Content-Type", "application/octet-stream")
+	responseWriter.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, name))
+}
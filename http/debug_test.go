@@ -0,0 +1,103 @@
+package pkghttp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDebugHandler(t *testing.T) {
+	handler := NewDebugHandler(
+		DebugOptions{
+			Vars: func() map[string]interface{} {
+				return map[string]interface{}{
+					"requests": 3,
+					"cmdline":  "ignored",
+				}
+			},
+		},
+	)
+	serve := func(method string, path string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(method, path, nil))
+		return recorder
+	}
+
+	recorder := serve("GET", "/debug/buildinfo")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Contains(t, recorder.Body.String(), `"go_version"`)
+
+	recorder = serve("GET", "/debug/goroutines")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Contains(t, recorder.Body.String(), "TestDebugHandler")
+
+	recorder = serve("GET", "/debug/vars")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	vars := make(map[string]interface{})
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &vars))
+	require.Equal(t, float64(3), vars["requests"])
+	require.Len(t, vars["cmdline"], len(os.Args))
+	require.NotNil(t, vars["memstats"])
+
+	recorder = serve("PUT", "/debug/loglevel?level=debug")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, "DEBUG", strings.TrimSpace(recorder.Body.String()))
+	recorder = serve("GET", "/debug/loglevel")
+	require.Equal(t, "DEBUG", strings.TrimSpace(recorder.Body.String()))
+	require.Equal(t, http.StatusBadRequest, serve("PUT", "/debug/loglevel?level=foo").Code)
+	require.Equal(t, http.StatusMethodNotAllowed, serve("DELETE", "/debug/loglevel").Code)
+	serve("PUT", "/debug/loglevel?level=info")
+
+	_, err := NewServer(http.NotFoundHandler(), HandlerEnv{Port: 8080, DebugPort: 8080}, ServerOptions{})
+	require.Error(t, err)
+}
+
+func TestDebugHandlerProfiles(t *testing.T) {
+	handler := NewDebugHandler(DebugOptions{})
+	serve := func(method string, path string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(method, path, nil))
+		return recorder
+	}
+
+	recorder := serve("GET", "/debug/pprof/")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Contains(t, recorder.Body.String(), "heap")
+
+	recorder = serve("GET", "/debug/pprof/goroutine?debug=1")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Contains(t, recorder.Body.String(), "TestDebugHandlerProfiles")
+
+	recorder = serve("GET", "/debug/pprof/heap")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, "application/octet-stream", recorder.Header().Get("Content-Type"))
+	require.NotEmpty(t, recorder.Body.Bytes())
+
+	require.Equal(t, http.StatusNotFound, serve("GET", "/debug/pprof/foo").Code)
+	require.Equal(t, http.StatusBadRequest, serve("GET", "/debug/pprof/profile?seconds=foo").Code)
+
+	recorder = serve("GET", "/debug/pprof/cmdline")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Contains(t, recorder.Body.String(), os.Args[0])
+}
+
+func TestDefaultServeMuxHasNoDebugRoutes(t *testing.T) {
+	handler := NewWrapperHandler(http.DefaultServeMux, HandlerEnv{})
+	for _, path := range []string{
+		"/debug/pprof/",
+		"/debug/pprof/cmdline",
+		"/debug/pprof/profile",
+		"/debug/vars",
+	} {
+		_, pattern := http.DefaultServeMux.Handler(httptest.NewRequest("GET", path, nil))
+		require.Equal(t, "", pattern, path)
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest("GET", path, nil))
+		require.Equal(t, http.StatusNotFound, recorder.Code, path)
+	}
+}
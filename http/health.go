@@ -0,0 +1,190 @@
+package pkghttp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	defaultHealthCheckTimeout = 5 * time.Second
+
+	healthStatusOK   = "ok"
+	healthStatusFail = "fail"
+)
+
+var errHealthCheckTimedOut = errors.New("pkghttp: health check timed out")
+
+type healthCheckEntry struct {
+	name    string
+	timeout time.Duration
+	check   HealthCheck
+}
+
+type healthRegistry struct {
+	lock            *sync.RWMutex
+	livenessChecks  []*healthCheckEntry
+	readinessChecks []*healthCheckEntry
+	shuttingDown    bool
+}
+
+func newHealthRegistry() *healthRegistry {
+	return &healthRegistry{
+		&sync.RWMutex{},
+		nil,
+		nil,
+		false,
+	}
+}
+
+func (h *healthRegistry) RegisterLiveness(name string, timeout time.Duration, check HealthCheck) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.livenessChecks = append(h.livenessChecks, newHealthCheckEntry(name, timeout, check))
+}
+
+func (h *healthRegistry) RegisterReadiness(name string, timeout time.Duration, check HealthCheck) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.readinessChecks = append(h.readinessChecks, newHealthCheckEntry(name, timeout, check))
+}
+
+func (h *healthRegistry) Liveness(ctx context.Context) *HealthReport {
+	h.lock.RLock()
+	checks := h.livenessChecks
+	h.lock.RUnlock()
+	return runHealthChecks(ctx, checks)
+}
+
+func (h *healthRegistry) Readiness(ctx context.Context) *HealthReport {
+	h.lock.RLock()
+	checks := h.readinessChecks
+	shuttingDown := h.shuttingDown
+	h.lock.RUnlock()
+	if shuttingDown {
+		return &HealthReport{
+			Status:       healthStatusFail,
+			ShuttingDown: true,
+		}
+	}
+	return runHealthChecks(ctx, checks)
+}
+
+func (h *healthRegistry) SetShuttingDown() {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.shuttingDown = true
+}
+
+func (h *healthRegistry) ClearShuttingDown() {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.shuttingDown = false
+}
+
+func newHealthCheckEntry(name string, timeout time.Duration, check HealthCheck) *healthCheckEntry {
+	if timeout == 0 {
+		timeout = defaultHealthCheckTimeout
+	}
+	return &healthCheckEntry{name, timeout, check}
+}
+
+func runHealthChecks(ctx context.Context, checks []*healthCheckEntry) *HealthReport {
+	report := &HealthReport{
+		Status: healthStatusOK,
+		Checks: make([]*HealthCheckResult, len(checks)),
+	}
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check *healthCheckEntry) {
+			defer wg.Done()
+			report.Checks[i] = runHealthCheck(ctx, check)
+		}(i, check)
+	}
+	wg.Wait()
+	for _, result := range report.Checks {
+		if result.Status != healthStatusOK {
+			report.Status = healthStatusFail
+		}
+	}
+	return report
+}
+
+func runHealthCheck(ctx context.Context, check *healthCheckEntry) *HealthCheckResult {
+	ctx, cancel := context.WithTimeout(ctx, check.timeout)
+	defer cancel()
+	start := time.Now()
+	// buffered so that a check that does not respect the context does not leak the goroutine forever
+	errC := make(chan error, 1)
+	go func() {
+		// a panicking check fails instead of taking down the server
+		defer func() {
+			if recoverErr := recover(); recoverErr != nil {
+				errC <- fmt.Errorf("health check %s panicked: %v", check.name, recoverErr)
+			}
+		}()
+		errC <- check.check(ctx)
+	}()
+	var err error
+	select {
+	case err = <-errC:
+	case <-ctx.Done():
+		err = errHealthCheckTimedOut
+	}
+	result := &HealthCheckResult{
+		Name:           check.name,
+		Status:         healthStatusOK,
+		LatencySeconds: time.Since(start).Seconds(),
+	}
+	if err != nil {
+		result.Status = healthStatusFail
+		result.Error = err.Error()
+	}
+	return result
+}
+
+func newHealthRegistryMiddleware(registry HealthRegistry, livenessPath string, readinessPath string) Middleware {
+	if livenessPath == "" {
+		livenessPath = "/health/live"
+	}
+	if readinessPath == "" {
+		readinessPath = "/health/ready"
+	}
+	return func(handler http.Handler) http.Handler {
+		return http.HandlerFunc(
+			func(responseWriter http.ResponseWriter, request *http.Request) {
+				if request.URL != nil {
+					switch request.URL.Path {
+					case livenessPath:
+						writeHealthReport(responseWriter, registry.Liveness(request.Context()))
+						return
+					case readinessPath:
+						writeHealthReport(responseWriter, registry.Readiness(request.Context()))
+						return
+					}
+				}
+				handler.ServeHTTP(responseWriter, request)
+			},
+		)
+	}
+}
+
+func writeHealthReport(responseWriter http.ResponseWriter, report *HealthReport) {
+	data, err := json.Marshal(report)
+	if err != nil {
+		ErrorInternal(responseWriter, err)
+		return
+	}
+	responseWriter.Header().Set("Content-Type", "application/json")
+	if report.Status == healthStatusOK {
+		responseWriter.WriteHeader(http.StatusOK)
+	} else {
+		responseWriter.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_, _ = responseWriter.Write(data)
+}
@@ -0,0 +1,67 @@
+package pkghttp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthRegistry(t *testing.T) {
+	registry := NewHealthRegistry()
+	registry.RegisterLiveness("ok", 0, func(context.Context) error { return nil })
+	registry.RegisterReadiness("db", 0, func(context.Context) error { return errors.New("down") })
+	registry.RegisterReadiness("slow", time.Millisecond, func(ctx context.Context) error {
+		<-ctx.Done()
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	})
+	handler := NewHealthRegistryMiddleware(registry, "", "")(http.NotFoundHandler())
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/health/live", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/health/ready", nil))
+	require.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	report := &HealthReport{}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), report))
+	require.Equal(t, "fail", report.Status)
+	require.Len(t, report.Checks, 2)
+	require.Equal(t, "down", report.Checks[0].Error)
+	require.Equal(t, errHealthCheckTimedOut.Error(), report.Checks[1].Error)
+
+	registry.SetShuttingDown()
+	report = registry.Readiness(context.Background())
+	require.True(t, report.ShuttingDown)
+	require.Len(t, report.Checks, 0)
+
+	registry.ClearShuttingDown()
+	report = registry.Readiness(context.Background())
+	require.False(t, report.ShuttingDown)
+	require.Len(t, report.Checks, 2)
+}
+
+func TestHealthRegistryPanic(t *testing.T) {
+	registry := NewHealthRegistry()
+	registry.RegisterReadiness("ok", 0, func(context.Context) error { return nil })
+	registry.RegisterReadiness("db", 0, func(context.Context) error { panic("boom") })
+	handler := NewHealthRegistryMiddleware(registry, "", "")(http.NotFoundHandler())
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/health/ready", nil))
+	require.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	report := &HealthReport{}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), report))
+	require.Equal(t, "fail", report.Status)
+	require.Len(t, report.Checks, 2)
+	require.Equal(t, "ok", report.Checks[0].Status)
+	require.Equal(t, "fail", report.Checks[1].Status)
+	require.Equal(t, "health check db panicked: boom", report.Checks[1].Error)
+}
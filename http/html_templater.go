@@ -0,0 +1,134 @@
+package pkghttp
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+
+	"go.pedge.io/lion/proto"
+)
+
+type htmlTemplater struct {
+	baseDirPath   string
+	opts          HTMLTemplaterOptions
+	funcMap       htmltemplate.FuncMap
+	templateCache map[string]*htmltemplate.Template
+	templateLock  *sync.RWMutex
+}
+
+func newHTMLTemplater(baseDirPath string, opts HTMLTemplaterOptions) *htmlTemplater {
+	if opts.Partials == "" {
+		opts.Partials = "partials/*.html"
+	}
+	return &htmlTemplater{
+		baseDirPath,
+		opts,
+		htmltemplate.FuncMap{
+			"lowercase": strings.ToLower,
+			"uppercase": strings.ToUpper,
+		},
+		make(map[string]*htmltemplate.Template),
+		&sync.RWMutex{},
+	}
+}
+
+func (h *htmlTemplater) WithFuncs(funcMap template.FuncMap) HTMLTemplater {
+	newFuncMap := make(htmltemplate.FuncMap)
+	for key, value := range h.funcMap {
+		newFuncMap[key] = value
+	}
+	for key, value := range funcMap {
+		newFuncMap[key] = value
+	}
+	return &htmlTemplater{
+		h.baseDirPath,
+		h.opts,
+		newFuncMap,
+		make(map[string]*htmltemplate.Template),
+		&sync.RWMutex{},
+	}
+}
+
+func (h *htmlTemplater) WithLayout(layout string) HTMLTemplater {
+	opts := h.opts
+	opts.Layout = layout
+	return &htmlTemplater{
+		h.baseDirPath,
+		opts,
+		h.funcMap,
+		make(map[string]*htmltemplate.Template),
+		&sync.RWMutex{},
+	}
+}
+
+func (h *htmlTemplater) Execute(responseWriter http.ResponseWriter, name string, data interface{}) {
+	// the page is rendered into a buffer so that an error does not leave a partial page
+	buffer := bytes.NewBuffer(nil)
+	if err := h.execute(buffer, name, data); err != nil {
+		protolion.Error(
+			&TemplateFailed{
+				Name:  name,
+				Error: err.Error(),
+			},
+		)
+		http.Error(responseWriter, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	responseWriter.Header().Set("Content-Type", "text/html; charset=utf-8")
+	responseWriter.Header().Set("Content-Length", strconv.Itoa(buffer.Len()))
+	_, _ = buffer.WriteTo(responseWriter)
+}
+
+func (h *htmlTemplater) execute(buffer *bytes.Buffer, name string, data interface{}) error {
+	t, err := h.getTemplate(name)
+	if err != nil {
+		return err
+	}
+	if h.opts.Layout != "" {
+		return t.ExecuteTemplate(buffer, filepath.Base(h.opts.Layout), data)
+	}
+	return t.ExecuteTemplate(buffer, filepath.Base(name), data)
+}
+
+func (h *htmlTemplater) getTemplate(name string) (*htmltemplate.Template, error) {
+	if !h.opts.NoCache {
+		h.templateLock.RLock()
+		if t, ok := h.templateCache[name]; ok {
+			h.templateLock.RUnlock()
+			return t, nil
+		}
+		h.templateLock.RUnlock()
+	}
+	// the layout is parsed first so that the blocks it defines are overridden by the page
+	var filePaths []string
+	if h.opts.Layout != "" {
+		filePaths = append(filePaths, filepath.Join(h.baseDirPath, h.opts.Layout))
+	}
+	partialFilePaths, err := filepath.Glob(filepath.Join(h.baseDirPath, h.opts.Partials))
+	if err != nil {
+		return nil, err
+	}
+	filePath := filepath.Join(h.baseDirPath, name)
+	for _, partialFilePath := range partialFilePaths {
+		if partialFilePath != filePath {
+			filePaths = append(filePaths, partialFilePath)
+		}
+	}
+	filePaths = append(filePaths, filePath)
+	t, err := htmltemplate.New(filepath.Base(name)).Funcs(h.funcMap).ParseFiles(filePaths...)
+	if err != nil {
+		return nil, fmt.Errorf("pkghttp: could not parse template %s: %s", name, err.Error())
+	}
+	if !h.opts.NoCache {
+		h.templateLock.Lock()
+		h.templateCache[name] = t
+		h.templateLock.Unlock()
+	}
+	return t, nil
+}
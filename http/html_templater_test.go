@@ -0,0 +1,58 @@
+package pkghttp
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"text/template"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTMLTemplater(t *testing.T) {
+	dirPath, err := ioutil.TempDir("", "pkghttp")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(dirPath)
+	}()
+	for filePath, content := range map[string]string{
+		"layouts/base.html":  `<title>{{block "title" .}}Default{{end}}</title>{{block "content" .}}{{end}}`,
+		"partials/name.html": `<b>{{.}}</b>`,
+		"index.html":         `{{define "title"}}Index{{end}}{{define "content"}}{{template "name.html" .Name}}{{end}}`,
+		"broken.html":        `{{define "content"}}{{.Missing.Field}}{{end}}`,
+		"plain.html":         `<p>{{.Name}}</p>`,
+		"shout.html":         `{{define "content"}}{{shout .Name}}{{end}}`,
+	} {
+		require.NoError(t, os.MkdirAll(filepath.Join(dirPath, filepath.Dir(filePath)), 0755))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dirPath, filePath), []byte(content), 0644))
+	}
+	templater := NewHTMLTemplater(dirPath, HTMLTemplaterOptions{Layout: "layouts/base.html"})
+	data := map[string]interface{}{"Name": "<script>alert(1)</script>"}
+
+	recorder := httptest.NewRecorder()
+	templater.Execute(recorder, "index.html", data)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, "text/html; charset=utf-8", recorder.Header().Get("Content-Type"))
+	require.Equal(t, "<title>Index</title><b>&lt;script&gt;alert(1)&lt;/script&gt;</b>", recorder.Body.String())
+
+	recorder = httptest.NewRecorder()
+	templater.Execute(recorder, "broken.html", map[string]interface{}{"Missing": 1})
+	require.Equal(t, http.StatusInternalServerError, recorder.Code)
+	require.Equal(t, "Internal Server Error\n", recorder.Body.String())
+
+	recorder = httptest.NewRecorder()
+	templater.WithLayout("").WithFuncs(nil).Execute(recorder, "plain.html", data)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, "<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>", recorder.Body.String())
+
+	recorder = httptest.NewRecorder()
+	templater.WithLayout("").
+		WithFuncs(template.FuncMap{"shout": func(s string) string { return s + "!" }}).
+		WithLayout("layouts/base.html").
+		Execute(recorder, "shout.html", map[string]interface{}{"Name": "foo"})
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, "<title>Default</title>foo!", recorder.Body.String())
+}
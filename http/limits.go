@@ -0,0 +1,190 @@
+package pkghttp
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type limitedBody struct {
+	io.ReadCloser
+	call     *Call
+	maxBytes int64
+	read     int64
+}
+
+func newMaxBodyBytesMiddleware(maxBytes int64) Middleware {
+	return func(handler http.Handler) http.Handler {
+		return http.HandlerFunc(
+			func(responseWriter http.ResponseWriter, request *http.Request) {
+				call := getCall(request.Context())
+				if request.ContentLength > maxBytes {
+					if call != nil {
+						call.LimitExceeded = fmt.Sprintf("body of %d bytes larger than %d bytes", request.ContentLength, maxBytes)
+					}
+					writeProblem(responseWriter, request, ErrRequestBodyTooLarge)
+					return
+				}
+				if request.Body != nil && request.Body != http.NoBody {
+					request.Body = &limitedBody{
+						http.MaxBytesReader(responseWriter, request.Body, maxBytes),
+						call,
+						maxBytes,
+						0,
+					}
+				}
+				handler.ServeHTTP(responseWriter, request)
+			},
+		)
+	}
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.read += int64(n)
+	if err == nil || err == io.EOF {
+		return n, err
+	}
+	if b.read >= b.maxBytes {
+		b.setLimitExceeded(fmt.Sprintf("body larger than %d bytes", b.maxBytes))
+		return n, ErrRequestBodyTooLarge
+	}
+	if isTimeout(err) {
+		b.setLimitExceeded("read timeout")
+	}
+	return n, err
+}
+
+func (b *limitedBody) setLimitExceeded(reason string) {
+	if b.call != nil && b.call.LimitExceeded == "" {
+		b.call.LimitExceeded = reason
+	}
+}
+
+func isTimeout(err error) bool {
+	netErr, ok := err.(net.Error)
+	return ok && netErr.Timeout()
+}
+
+type limitConnKey struct{}
+
+type writeDeadlineKey struct{}
+
+// limitListener wraps the conns it accepts with a limitConn.
+type limitListener struct {
+	net.Listener
+}
+
+func (l *limitListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return newLimitConn(conn), nil
+}
+
+// limitConn records what was read since the conn was last idle, so that requests
+// that net/http rejects before they reach the handler, such as for headers that
+// are too large or too slow, can be logged.
+type limitConn struct {
+	net.Conn
+	lock         *sync.Mutex
+	bytesRead    int64
+	readTimedOut bool
+	served       bool
+}
+
+func newLimitConn(conn net.Conn) *limitConn {
+	return &limitConn{conn, &sync.Mutex{}, 0, false, false}
+}
+
+func (c *limitConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	c.lock.Lock()
+	c.bytesRead += int64(n)
+	if isTimeout(err) {
+		c.readTimedOut = true
+	}
+	c.lock.Unlock()
+	return n, err
+}
+
+func (c *limitConn) setServed() {
+	c.lock.Lock()
+	c.served = true
+	c.lock.Unlock()
+}
+
+func (c *limitConn) reset() {
+	c.lock.Lock()
+	c.bytesRead = 0
+	c.readTimedOut = false
+	c.served = false
+	c.lock.Unlock()
+}
+
+// headerLimitExceeded returns the limit exceeded by the request that was read when
+// the conn closed without the request reaching the handler, and the status code
+// written by net/http, or "" if no limit was exceeded.
+//
+// A conn that is closed without reading anything is an idle conn, not a slow request.
+func (c *limitConn) headerLimitExceeded(maxHeaderBytes int64) (string, int) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.served || c.bytesRead == 0 {
+		return "", 0
+	}
+	if c.bytesRead >= maxHeaderBytes {
+		return fmt.Sprintf("header larger than %d bytes", maxHeaderBytes), http.StatusRequestHeaderFieldsTooLarge
+	}
+	if c.readTimedOut {
+		return "read header timeout", 0
+	}
+	return "", 0
+}
+
+// toLimitConn returns the limitConn of conn, which may be wrapped by a *tls.Conn,
+// or nil if conn was not accepted by a limitListener.
+func toLimitConn(conn net.Conn) *limitConn {
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		conn = tlsConn.NetConn()
+	}
+	limitConn, _ := conn.(*limitConn)
+	return limitConn
+}
+
+func limitConnContext(ctx context.Context, conn net.Conn) context.Context {
+	if limitConn := toLimitConn(conn); limitConn != nil {
+		return context.WithValue(ctx, limitConnKey{}, limitConn)
+	}
+	return ctx
+}
+
+// newLimitConnHandler marks the conn of every request as served, and adds the
+// deadline for writing the response to the context of the request.
+func newLimitConnHandler(handler http.Handler, writeTimeout time.Duration) http.Handler {
+	return http.HandlerFunc(
+		func(responseWriter http.ResponseWriter, request *http.Request) {
+			ctx := request.Context()
+			if limitConn, ok := ctx.Value(limitConnKey{}).(*limitConn); ok {
+				limitConn.setServed()
+			}
+			if writeTimeout > 0 {
+				request = request.WithContext(context.WithValue(ctx, writeDeadlineKey{}, time.Now().Add(writeTimeout)))
+			}
+			handler.ServeHTTP(responseWriter, request)
+		},
+	)
+}
+
+// writeDeadlinePassed returns true if the deadline for writing the response has
+// passed, in which case a response that is still buffered cannot be written.
+func writeDeadlinePassed(ctx context.Context) bool {
+	writeDeadline, ok := ctx.Value(writeDeadlineKey{}).(time.Time)
+	return ok && time.Now().After(writeDeadline)
+}
@@ -0,0 +1,181 @@
+package pkghttp
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaxBodyBytes(t *testing.T) {
+	var call *Call
+	var readErr error
+	handler := NewChain(
+		NewCallLogMiddleware(CallLogOptions{}),
+		func(handler http.Handler) http.Handler {
+			return http.HandlerFunc(
+				func(responseWriter http.ResponseWriter, request *http.Request) {
+					call = CallFromContext(request.Context())
+					handler.ServeHTTP(responseWriter, request)
+				},
+			)
+		},
+		NewMaxBodyBytesMiddleware(4),
+	).Then(
+		ErrorHandlerFunc(
+			func(responseWriter http.ResponseWriter, request *http.Request) error {
+				_, readErr = ioutil.ReadAll(request.Body)
+				return readErr
+			},
+		),
+	)
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("POST", "/", strings.NewReader("foo")))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.NoError(t, readErr)
+	require.Equal(t, "", call.LimitExceeded)
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("POST", "/", strings.NewReader("foobar")))
+	require.Equal(t, http.StatusRequestEntityTooLarge, recorder.Code)
+	require.Equal(t, "body of 6 bytes larger than 4 bytes", call.LimitExceeded)
+
+	recorder = httptest.NewRecorder()
+	request := httptest.NewRequest("POST", "/", strings.NewReader("foobar"))
+	request.ContentLength = -1
+	handler.ServeHTTP(recorder, request)
+	require.Equal(t, ErrRequestBodyTooLarge, readErr)
+	require.Equal(t, http.StatusRequestEntityTooLarge, recorder.Code)
+	require.Equal(t, "body larger than 4 bytes", call.LimitExceeded)
+}
+
+func TestServerHeaderLimits(t *testing.T) {
+	port := testFreePort(t)
+	server, err := newServer(
+		http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}),
+		HandlerEnv{Port: port, MaxHeaderBytes: 1024, ReadHeaderTimeoutSec: 1},
+		ServerOptions{},
+	)
+	require.NoError(t, err)
+	callC := make(chan *Call, 4)
+	server.logCall = func(call *Call) { callC <- call }
+	ctx, cancel := context.WithCancel(context.Background())
+	errC := make(chan error, 1)
+	go func() {
+		errC <- server.Serve(ctx)
+	}()
+	address := fmt.Sprintf("localhost:%d", port)
+	var conn net.Conn
+	for i := 0; i < 100; i++ {
+		if conn, err = net.Dial("tcp", address); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.NoError(t, err)
+
+	// served requests are not logged by the server
+	_, err = fmt.Fprint(conn, "GET / HTTP/1.1\r\nHost: localhost\r\n\r\n")
+	require.NoError(t, err)
+	response, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	require.NoError(t, conn.Close())
+
+	conn, err = net.Dial("tcp", address)
+	require.NoError(t, err)
+	_, err = fmt.Fprintf(conn, "GET / HTTP/1.1\r\nHost: localhost\r\nX-Foo: %s\r\n\r\n", strings.Repeat("a", 8192))
+	require.NoError(t, err)
+	response, err = http.ReadResponse(bufio.NewReader(conn), nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusRequestHeaderFieldsTooLarge, response.StatusCode)
+	require.NoError(t, conn.Close())
+	call := <-callC
+	require.Equal(t, uint32(http.StatusRequestHeaderFieldsTooLarge), call.StatusCode)
+	require.Equal(t, "header larger than 1024 bytes", call.LimitExceeded)
+
+	conn, err = net.Dial("tcp", address)
+	require.NoError(t, err)
+	_, err = fmt.Fprint(conn, "GET / HTTP/1.1\r\nHost: local")
+	require.NoError(t, err)
+	_, err = ioutil.ReadAll(conn)
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+	call = <-callC
+	require.Equal(t, "read header timeout", call.LimitExceeded)
+
+	cancel()
+	require.NoError(t, <-errC)
+	select {
+	case call := <-callC:
+		t.Fatalf("unexpected call %v", call)
+	default:
+	}
+}
+
+func TestServerWriteTimeout(t *testing.T) {
+	port := testFreePort(t)
+	var call *Call
+	callC := make(chan *Call, 1)
+	server, err := NewServer(
+		http.HandlerFunc(
+			func(responseWriter http.ResponseWriter, request *http.Request) {
+				time.Sleep(1100 * time.Millisecond)
+				_, _ = responseWriter.Write([]byte("hello"))
+			},
+		),
+		HandlerEnv{Port: port, WriteTimeoutSec: 1},
+		ServerOptions{
+			Chain: NewChain(
+				func(handler http.Handler) http.Handler {
+					return http.HandlerFunc(
+						func(responseWriter http.ResponseWriter, request *http.Request) {
+							handler.ServeHTTP(responseWriter, request)
+							callC <- call
+						},
+					)
+				},
+				NewCallLogMiddleware(CallLogOptions{}),
+				func(handler http.Handler) http.Handler {
+					return http.HandlerFunc(
+						func(responseWriter http.ResponseWriter, request *http.Request) {
+							call = CallFromContext(request.Context())
+							handler.ServeHTTP(responseWriter, request)
+						},
+					)
+				},
+			),
+		},
+	)
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	errC := make(chan error, 1)
+	go func() {
+		errC <- server.Serve(ctx)
+	}()
+	for i := 0; i < 100; i++ {
+		var response *http.Response
+		if response, err = http.Get(fmt.Sprintf("http://localhost:%d/", port)); err == nil {
+			_ = response.Body.Close()
+		}
+		if err == nil || !strings.Contains(err.Error(), "connection refused") {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	// the small response is buffered, and is never written
+	require.Error(t, err)
+	call = <-callC
+	require.Equal(t, "write timeout", call.LimitExceeded)
+	cancel()
+	require.NoError(t, <-errC)
+}
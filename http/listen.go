@@ -0,0 +1,208 @@
+package pkghttp
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	listenNetworkTCP     = "tcp"
+	listenNetworkUnix    = "unix"
+	listenNetworkSystemd = "systemd"
+
+	// the first file descriptor passed by systemd
+	systemdListenFDsStart = 3
+)
+
+type listenAddress struct {
+	network string
+	// the address, or the file descriptor name for systemd, where "" is all file descriptors
+	address string
+}
+
+func (l listenAddress) String() string {
+	if l.network == listenNetworkTCP {
+		return l.address
+	}
+	if l.address == "" {
+		return l.network
+	}
+	return fmt.Sprintf("%s:%s", l.network, l.address)
+}
+
+func parseListenAddresses(handlerEnv HandlerEnv) ([]listenAddress, error) {
+	addresses := splitList(handlerEnv.Addresses)
+	if len(addresses) == 0 {
+		return []listenAddress{{listenNetworkTCP, fmt.Sprintf(":%d", handlerEnv.Port)}}, nil
+	}
+	listenAddresses := make([]listenAddress, len(addresses))
+	for i, address := range addresses {
+		switch {
+		case strings.HasPrefix(address, "unix:"):
+			path := strings.TrimPrefix(address, "unix:")
+			if path == "" {
+				return nil, fmt.Errorf("pkghttp: invalid address %s, no socket path", address)
+			}
+			listenAddresses[i] = listenAddress{listenNetworkUnix, path}
+		case address == listenNetworkSystemd:
+			listenAddresses[i] = listenAddress{listenNetworkSystemd, ""}
+		case strings.HasPrefix(address, "systemd:"):
+			listenAddresses[i] = listenAddress{listenNetworkSystemd, strings.TrimPrefix(address, "systemd:")}
+		default:
+			if _, _, err := net.SplitHostPort(address); err != nil {
+				return nil, fmt.Errorf("pkghttp: invalid address %s: %s", address, err.Error())
+			}
+			listenAddresses[i] = listenAddress{listenNetworkTCP, address}
+		}
+	}
+	return listenAddresses, nil
+}
+
+func parseUnixSocketMode(s string) (os.FileMode, error) {
+	if s == "" {
+		return 0660, nil
+	}
+	mode, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || mode > 0777 {
+		return 0, fmt.Errorf("pkghttp: invalid unix socket mode %s, must be octal such as 0660", s)
+	}
+	return os.FileMode(mode), nil
+}
+
+// listen returns a listener for every address. For systemd addresses, this is
+// every inherited file descriptor with the name, which may be more than one.
+func listen(listenAddresses []listenAddress, unixSocketMode os.FileMode) (_ []net.Listener, retErr error) {
+	var listeners []net.Listener
+	var systemdListeners []*systemdListener
+	defer func() {
+		if retErr != nil {
+			for _, listener := range listeners {
+				_ = listener.Close()
+			}
+			for _, systemdListener := range systemdListeners {
+				_ = systemdListener.Close()
+			}
+		}
+	}()
+	for _, listenAddress := range listenAddresses {
+		switch listenAddress.network {
+		case listenNetworkTCP:
+			listener, err := net.Listen("tcp", listenAddress.address)
+			if err != nil {
+				return nil, err
+			}
+			listeners = append(listeners, listener)
+		case listenNetworkUnix:
+			listener, err := listenUnix(listenAddress.address, unixSocketMode)
+			if err != nil {
+				return nil, err
+			}
+			listeners = append(listeners, listener)
+		case listenNetworkSystemd:
+			if systemdListeners == nil {
+				var err error
+				if systemdListeners, err = readSystemdListeners(); err != nil {
+					return nil, err
+				}
+			}
+			found := false
+			for _, systemdListener := range systemdListeners {
+				if listenAddress.address == "" || systemdListener.name == listenAddress.address {
+					systemdListener.used = true
+					found = true
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("pkghttp: no file descriptors passed by systemd for %s", listenAddress.String())
+			}
+		}
+	}
+	for _, systemdListener := range systemdListeners {
+		if systemdListener.used {
+			listeners = append(listeners, systemdListener.Listener)
+		} else {
+			// not asked for
+			_ = systemdListener.Close()
+		}
+	}
+	return listeners, nil
+}
+
+func listenUnix(path string, mode os.FileMode) (net.Listener, error) {
+	if fileInfo, err := os.Stat(path); err == nil {
+		if fileInfo.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("pkghttp: %s exists and is not a socket", path)
+		}
+		if conn, err := net.Dial("unix", path); err == nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("pkghttp: %s is already in use", path)
+		}
+		// left behind by a server that did not shut down cleanly
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, mode); err != nil {
+		_ = listener.Close()
+		return nil, err
+	}
+	return listener, nil
+}
+
+type systemdListener struct {
+	net.Listener
+	name string
+	used bool
+}
+
+// readSystemdListeners reads the listeners passed by systemd socket activation
+// with LISTEN_PID, LISTEN_FDS, and LISTEN_FDNAMES, and unsets these variables
+// so that they are not passed to child processes.
+func readSystemdListeners() ([]*systemdListener, error) {
+	listenPID := os.Getenv("LISTEN_PID")
+	listenFDs := os.Getenv("LISTEN_FDS")
+	listenFDNames := os.Getenv("LISTEN_FDNAMES")
+	_ = os.Unsetenv("LISTEN_PID")
+	_ = os.Unsetenv("LISTEN_FDS")
+	_ = os.Unsetenv("LISTEN_FDNAMES")
+	if listenFDs == "" {
+		return nil, fmt.Errorf("pkghttp: no file descriptors passed by systemd, LISTEN_FDS not set")
+	}
+	if listenPID != strconv.Itoa(os.Getpid()) {
+		return nil, fmt.Errorf("pkghttp: file descriptors passed by systemd are for pid %s, not %d", listenPID, os.Getpid())
+	}
+	numFDs, err := strconv.Atoi(listenFDs)
+	if err != nil || numFDs < 1 {
+		return nil, fmt.Errorf("pkghttp: invalid LISTEN_FDS %s", listenFDs)
+	}
+	var names []string
+	if listenFDNames != "" {
+		names = strings.Split(listenFDNames, ":")
+	}
+	systemdListeners := make([]*systemdListener, 0, numFDs)
+	for i := 0; i < numFDs; i++ {
+		name := "unknown"
+		if i < len(names) {
+			name = names[i]
+		}
+		file := os.NewFile(uintptr(systemdListenFDsStart+i), name)
+		// net.FileListener duplicates the file descriptor
+		listener, err := net.FileListener(file)
+		_ = file.Close()
+		if err != nil {
+			for _, systemdListener := range systemdListeners {
+				_ = systemdListener.Close()
+			}
+			return nil, fmt.Errorf("pkghttp: file descriptor %d passed by systemd: %s", systemdListenFDsStart+i, err.Error())
+		}
+		systemdListeners = append(systemdListeners, &systemdListener{listener, name, false})
+	}
+	return systemdListeners, nil
+}
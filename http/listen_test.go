@@ -0,0 +1,100 @@
+package pkghttp
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerAddresses(t *testing.T) {
+	dirPath, err := ioutil.TempDir("", "pkghttp")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(dirPath)
+	}()
+	socketPath := filepath.Join(dirPath, "http.sock")
+	port := testFreePort(t)
+	server, err := NewServer(
+		http.HandlerFunc(
+			func(responseWriter http.ResponseWriter, request *http.Request) {
+				_, _ = responseWriter.Write([]byte("hello"))
+			},
+		),
+		HandlerEnv{
+			Addresses:      fmt.Sprintf("localhost:%d, unix:%s", port, socketPath),
+			UnixSocketMode: "0600",
+		},
+		ServerOptions{},
+	)
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	errC := make(chan error, 1)
+	go func() {
+		errC <- server.Serve(ctx)
+	}()
+	unixClient := &http.Client{
+		Transport: &http.Transport{
+			Dial: func(string, string) (net.Conn, error) {
+				return net.Dial("unix", socketPath)
+			},
+		},
+	}
+	for _, get := range []func() (*http.Response, error){
+		func() (*http.Response, error) { return http.Get(fmt.Sprintf("http://localhost:%d/foo", port)) },
+		func() (*http.Response, error) { return unixClient.Get("http://unix/foo") },
+	} {
+		var response *http.Response
+		for i := 0; i < 100; i++ {
+			if response, err = get(); err == nil {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		require.NoError(t, err)
+		data, err := ioutil.ReadAll(response.Body)
+		require.NoError(t, err)
+		require.NoError(t, response.Body.Close())
+		require.Equal(t, "hello", string(data))
+	}
+	fileInfo, err := os.Stat(socketPath)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0600), fileInfo.Mode().Perm())
+	cancel()
+	require.NoError(t, <-errC)
+	_, err = os.Stat(socketPath)
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestParseListenAddresses(t *testing.T) {
+	listenAddresses, err := parseListenAddresses(HandlerEnv{Port: 1234})
+	require.NoError(t, err)
+	require.Equal(t, []listenAddress{{listenNetworkTCP, ":1234"}}, listenAddresses)
+	listenAddresses, err = parseListenAddresses(HandlerEnv{Addresses: "127.0.0.1:80,unix:/tmp/a.sock,systemd,systemd:http"})
+	require.NoError(t, err)
+	require.Equal(
+		t,
+		[]listenAddress{
+			{listenNetworkTCP, "127.0.0.1:80"},
+			{listenNetworkUnix, "/tmp/a.sock"},
+			{listenNetworkSystemd, ""},
+			{listenNetworkSystemd, "http"},
+		},
+		listenAddresses,
+	)
+	_, err = parseListenAddresses(HandlerEnv{Addresses: "foo"})
+	require.Error(t, err)
+	_, err = parseListenAddresses(HandlerEnv{Addresses: "unix:"})
+	require.Error(t, err)
+	_, err = parseUnixSocketMode("999")
+	require.Error(t, err)
+	_, err = listen([]listenAddress{{listenNetworkSystemd, ""}}, 0660)
+	require.Error(t, err)
+}
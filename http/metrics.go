@@ -0,0 +1,224 @@
+package pkghttp
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.pedge.io/pb/go/google/protobuf"
+)
+
+const (
+	defaultMetricsRoute = "*"
+	otherMetricsMethod  = "OTHER"
+)
+
+var (
+	defaultMetricsBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+	// methods outside of these are labeled OTHER, so that clients cannot create
+	// an unbounded number of series
+	metricsMethods = map[string]bool{
+		"CONNECT": true,
+		"DELETE":  true,
+		"GET":     true,
+		"HEAD":    true,
+		"OPTIONS": true,
+		"PATCH":   true,
+		"POST":    true,
+		"PUT":     true,
+		"TRACE":   true,
+	}
+)
+
+type metricsMiddleware struct {
+	path      string
+	routeFunc func(*http.Request) string
+	buckets   []float64
+	lock      *sync.Mutex
+	requests  map[string]*metricsRequests
+	inFlight  map[string]*metricsInFlight
+}
+
+type metricsRequests struct {
+	labels       []string
+	count        uint64
+	sum          float64
+	bucketCounts []uint64
+}
+
+type metricsInFlight struct {
+	labels []string
+	count  int64
+}
+
+func newMetricsMiddleware(opts MetricsOptions) Middleware {
+	m := &metricsMiddleware{
+		opts.Path,
+		opts.RouteFunc,
+		opts.Buckets,
+		&sync.Mutex{},
+		make(map[string]*metricsRequests),
+		make(map[string]*metricsInFlight),
+	}
+	if m.path == "" {
+		m.path = "/metrics"
+	}
+	if m.routeFunc == nil {
+		m.routeFunc = defaultRouteFunc
+	}
+	if len(m.buckets) == 0 {
+		m.buckets = defaultMetricsBuckets
+	}
+	m.buckets = append([]float64{}, m.buckets...)
+	sort.Float64s(m.buckets)
+	return m.wrap
+}
+
+func (m *metricsMiddleware) wrap(handler http.Handler) http.Handler {
+	return http.HandlerFunc(
+		func(responseWriter http.ResponseWriter, request *http.Request) {
+			if request.URL != nil && request.URL.Path == m.path {
+				m.serveMetrics(responseWriter)
+				return
+			}
+			method := request.Method
+			if !metricsMethods[method] {
+				method = otherMetricsMethod
+			}
+			route := m.routeFunc(request)
+			m.addInFlight(method, route, 1)
+			responseWriter, request, state := withCallState(responseWriter, request)
+			defer func() {
+				m.addInFlight(method, route, -1)
+				state.finish()
+				m.observe(
+					method,
+					route,
+					strconv.Itoa(int(state.call.StatusCode)),
+					durationSeconds(state.call.Duration),
+				)
+			}()
+			handler.ServeHTTP(responseWriter, request)
+		},
+	)
+}
+
+func (m *metricsMiddleware) addInFlight(method string, route string, delta int64) {
+	labels := []string{"method", method, "route", route}
+	key := strings.Join(labels, "\x00")
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	inFlight, ok := m.inFlight[key]
+	if !ok {
+		inFlight = &metricsInFlight{labels: labels}
+		m.inFlight[key] = inFlight
+	}
+	inFlight.count += delta
+}
+
+func (m *metricsMiddleware) observe(method string, route string, status string, seconds float64) {
+	labels := []string{"method", method, "route", route, "status", status}
+	key := strings.Join(labels, "\x00")
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	requests, ok := m.requests[key]
+	if !ok {
+		requests = &metricsRequests{
+			labels:       labels,
+			bucketCounts: make([]uint64, len(m.buckets)),
+		}
+		m.requests[key] = requests
+	}
+	requests.count++
+	requests.sum += seconds
+	for i, bucket := range m.buckets {
+		if seconds <= bucket {
+			requests.bucketCounts[i]++
+		}
+	}
+}
+
+func (m *metricsMiddleware) serveMetrics(responseWriter http.ResponseWriter) {
+	buffer := bytes.NewBuffer(nil)
+	m.lock.Lock()
+	requestsKeys := make([]string, 0, len(m.requests))
+	for key := range m.requests {
+		requestsKeys = append(requestsKeys, key)
+	}
+	sort.Strings(requestsKeys)
+	inFlightKeys := make([]string, 0, len(m.inFlight))
+	for key := range m.inFlight {
+		inFlightKeys = append(inFlightKeys, key)
+	}
+	sort.Strings(inFlightKeys)
+
+	fmt.Fprintln(buffer, "# HELP http_requests_total The total number of HTTP requests.")
+	fmt.Fprintln(buffer, "# TYPE http_requests_total counter")
+	for _, key := range requestsKeys {
+		requests := m.requests[key]
+		fmt.Fprintf(buffer, "http_requests_total%s %d\n", formatLabels(requests.labels), requests.count)
+	}
+	fmt.Fprintln(buffer, "# HELP http_request_duration_seconds The HTTP request latencies in seconds.")
+	fmt.Fprintln(buffer, "# TYPE http_request_duration_seconds histogram")
+	for _, key := range requestsKeys {
+		requests := m.requests[key]
+		for i, bucket := range m.buckets {
+			fmt.Fprintf(
+				buffer,
+				"http_request_duration_seconds_bucket%s %d\n",
+				formatLabels(append(requests.labels, "le", formatFloat(bucket))),
+				requests.bucketCounts[i],
+			)
+		}
+		fmt.Fprintf(
+			buffer,
+			"http_request_duration_seconds_bucket%s %d\n",
+			formatLabels(append(requests.labels, "le", "+Inf")),
+			requests.count,
+		)
+		fmt.Fprintf(buffer, "http_request_duration_seconds_sum%s %s\n", formatLabels(requests.labels), formatFloat(requests.sum))
+		fmt.Fprintf(buffer, "http_request_duration_seconds_count%s %d\n", formatLabels(requests.labels), requests.count)
+	}
+	fmt.Fprintln(buffer, "# HELP http_requests_in_flight The number of HTTP requests being served.")
+	fmt.Fprintln(buffer, "# TYPE http_requests_in_flight gauge")
+	for _, key := range inFlightKeys {
+		inFlight := m.inFlight[key]
+		fmt.Fprintf(buffer, "http_requests_in_flight%s %d\n", formatLabels(inFlight.labels), inFlight.count)
+	}
+	m.lock.Unlock()
+	responseWriter.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = responseWriter.Write(buffer.Bytes())
+}
+
+func durationSeconds(duration *google_protobuf.Duration) float64 {
+	if duration == nil {
+		return 0
+	}
+	return float64(duration.Seconds) + float64(duration.Nanos)/1e9
+}
+
+func defaultRouteFunc(request *http.Request) string {
+	return defaultMetricsRoute
+}
+
+// labels are key, value, key, value...
+func formatLabels(labels []string) string {
+	pairs := make([]string, 0, len(labels)/2)
+	for i := 0; i+1 < len(labels); i += 2 {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, labels[i], escapeLabelValue(labels[i+1])))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func escapeLabelValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
@@ -0,0 +1,97 @@
+package pkghttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsMiddleware(t *testing.T) {
+	handler := NewChain(
+		NewMetricsMiddleware(MetricsOptions{Buckets: []float64{10, 0.05}}),
+		NewCallLogMiddleware(CallLogOptions{}),
+	).Then(
+		http.HandlerFunc(
+			func(responseWriter http.ResponseWriter, request *http.Request) {
+				switch request.URL.Path {
+				case "/slow":
+					time.Sleep(100 * time.Millisecond)
+				case "/missing":
+					http.NotFound(responseWriter, request)
+				}
+			},
+		),
+	)
+	serve := func(method string, path string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(method, path, nil))
+		return recorder
+	}
+	serve("GET", "/a")
+	serve("GET", "/b")
+	serve("POST", "/slow")
+	serve("GET", "/missing")
+	serve("FOO", "/a")
+
+	recorder := serve("GET", "/metrics")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, "text/plain; version=0.0.4; charset=utf-8", recorder.Header().Get("Content-Type"))
+	lines := strings.Split(strings.TrimSpace(recorder.Body.String()), "\n")
+	for _, expected := range []string{
+		"# TYPE http_requests_total counter",
+		`http_requests_total{method="GET",route="*",status="200"} 2`,
+		`http_requests_total{method="GET",route="*",status="404"} 1`,
+		`http_requests_total{method="OTHER",route="*",status="200"} 1`,
+		`http_requests_total{method="POST",route="*",status="200"} 1`,
+		"# TYPE http_request_duration_seconds histogram",
+		`http_request_duration_seconds_bucket{method="GET",route="*",status="200",le="0.05"} 2`,
+		`http_request_duration_seconds_bucket{method="GET",route="*",status="200",le="10"} 2`,
+		`http_request_duration_seconds_bucket{method="GET",route="*",status="200",le="+Inf"} 2`,
+		`http_request_duration_seconds_count{method="GET",route="*",status="200"} 2`,
+		`http_request_duration_seconds_bucket{method="POST",route="*",status="200",le="0.05"} 0`,
+		`http_request_duration_seconds_bucket{method="POST",route="*",status="200",le="10"} 1`,
+		`http_request_duration_seconds_bucket{method="POST",route="*",status="200",le="+Inf"} 1`,
+		"# TYPE http_requests_in_flight gauge",
+		`http_requests_in_flight{method="GET",route="*"} 0`,
+	} {
+		require.Contains(t, lines, expected)
+	}
+	for _, line := range lines {
+		if strings.HasPrefix(line, `http_request_duration_seconds_sum{method="POST"`) {
+			sum, err := strconv.ParseFloat(line[strings.LastIndex(line, " ")+1:], 64)
+			require.NoError(t, err)
+			require.True(t, sum >= 0.1, line)
+		}
+		require.False(t, strings.Contains(line, `route="/`), line)
+	}
+}
+
+func TestMetricsMiddlewareRouteFunc(t *testing.T) {
+	handler := NewMetricsMiddleware(
+		MetricsOptions{
+			Path: "/custom",
+			RouteFunc: func(request *http.Request) string {
+				if strings.HasPrefix(request.URL.Path, "/users/") {
+					return "/users/:id"
+				}
+				return "other"
+			},
+		},
+	)(http.NotFoundHandler())
+	for _, path := range []string{"/users/1", "/users/2", "/foo\"bar"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/custom", nil))
+	body := recorder.Body.String()
+	require.Contains(t, body, `http_requests_total{method="GET",route="/users/:id",status="404"} 2`)
+	require.Contains(t, body, `http_requests_total{method="GET",route="other",status="404"} 1`)
+	// 11 default buckets plus +Inf for each of the two series
+	require.Equal(t, 24, strings.Count(body, "http_request_duration_seconds_bucket{"))
+	require.Equal(t, `a\\b\"c\nd`, escapeLabelValue("a\\b\"c\nd"))
+}
@@ -0,0 +1,211 @@
+package pkghttp
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.pedge.io/pb/go/google/protobuf"
+)
+
+type callStateKey struct{}
+
+// callState is shared by all middlewares for a request.
+type callState struct {
+	call *Call
+	// the request as received by the innermost handler, which may have
+	// been copied by middlewares, and so may have a parsed form the
+	// outer request does not have
+	request *http.Request
+	// the response writer of the outermost middleware that uses the call state
+	responseWriter wrapperResponseWriter
+	start          time.Time
+	// set if the Call is logged by a call log middleware
+	logged bool
+}
+
+// withCallState returns the response writer and request to pass to the next handler,
+// and the callState for the request, which is created if this is the outermost
+// middleware that uses the call state.
+func withCallState(responseWriter http.ResponseWriter, request *http.Request) (http.ResponseWriter, *http.Request, *callState) {
+	if state := getCallState(request.Context()); state != nil {
+		return responseWriter, request, state
+	}
+	wrapperResponseWriter, responseWriter := newWrapperResponseWriter(responseWriter)
+	state := &callState{
+		&Call{},
+		request,
+		wrapperResponseWriter,
+		time.Now(),
+		false,
+	}
+	return responseWriter, request.WithContext(context.WithValue(request.Context(), callStateKey{}, state)), state
+}
+
+// finish sets the fields of the Call that describe the response, if not already set.
+func (s *callState) finish() {
+	call := s.call
+	if call.StatusCode == 0 {
+		call.StatusCode = uint32(statusCode(s.responseWriter.StatusCode()))
+	}
+	if call.Error == "" {
+		call.Error = errorString(s.responseWriter.WriteError())
+	}
+	if call.LimitExceeded == "" && (isTimeout(s.responseWriter.WriteError()) || writeDeadlinePassed(s.request.Context())) {
+		call.LimitExceeded = "write timeout"
+	}
+	if call.Duration == nil {
+		call.Duration = google_protobuf.DurationToProto(time.Since(s.start))
+	}
+}
+
+func getCallState(ctx context.Context) *callState {
+	state, _ := ctx.Value(callStateKey{}).(*callState)
+	return state
+}
+
+// getCall returns the Call for the request if it is logged.
+func getCall(ctx context.Context) *Call {
+	if state := getCallState(ctx); state != nil && state.logged {
+		return state.call
+	}
+	return nil
+}
+
+func chainThen(chain Chain, handler http.Handler) http.Handler {
+	handler = newCaptureRequestHandler(handler)
+	for i := len(chain) - 1; i >= 0; i-- {
+		handler = chain[i](handler)
+	}
+	return handler
+}
+
+func newCaptureRequestHandler(handler http.Handler) http.Handler {
+	return http.HandlerFunc(
+		func(responseWriter http.ResponseWriter, request *http.Request) {
+			if state := getCallState(request.Context()); state != nil {
+				state.request = request
+			}
+			handler.ServeHTTP(responseWriter, request)
+		},
+	)
+}
+
+func newHealthCheckMiddleware(healthCheckPath string) Middleware {
+	if healthCheckPath == "" {
+		healthCheckPath = "/health"
+	}
+	return func(handler http.Handler) http.Handler {
+		return http.HandlerFunc(
+			func(responseWriter http.ResponseWriter, request *http.Request) {
+				if request.URL != nil && request.URL.Path == healthCheckPath {
+					responseWriter.WriteHeader(http.StatusOK)
+					return
+				}
+				handler.ServeHTTP(responseWriter, request)
+			},
+		)
+	}
+}
+
+func valuesMap(values map[string][]string) map[string]string {
+	if values == nil {
+		return nil
+	}
+	m := make(map[string]string)
+	for key, value := range values {
+		m[key] = strings.Join(value, " ")
+	}
+	return m
+}
+
+func statusCode(code int) int {
+	if code == 0 {
+		return http.StatusOK
+	}
+	return code
+}
+
+func errorString(err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// newDefaultChain returns the Chain for NewDefaultChain and the first error in
+// handlerEnv, where the middlewares for invalid fields are either created with
+// their defaults or not added.
+func newDefaultChain(handlerEnv HandlerEnv) (Chain, error) {
+	var firstErr error
+	callLogOptions, err := NewCallLogOptions(handlerEnv)
+	if err != nil {
+		firstErr = err
+		callLogOptions = CallLogOptions{}
+	}
+	recoverFormat, err := newRecoverFormat(handlerEnv.RecoverFormat)
+	if err != nil {
+		if firstErr == nil {
+			firstErr = err
+		}
+		recoverFormat = RecoverFormatJSON
+	}
+	chain := NewChain(
+		NewHealthCheckMiddleware(handlerEnv.HealthCheckPath),
+		NewHealthRegistryMiddleware(
+			DefaultHealthRegistry,
+			handlerEnv.LivenessPath,
+			handlerEnv.ReadinessPath,
+		),
+	)
+	if handlerEnv.MetricsPath != "" {
+		chain = chain.Append(NewMetricsMiddleware(MetricsOptions{Path: handlerEnv.MetricsPath}))
+	}
+	chain = chain.Append(
+		NewCallLogMiddleware(callLogOptions),
+		NewRequestIDMiddleware(),
+	)
+	if handlerEnv.MaxBodyBytes != 0 {
+		chain = chain.Append(NewMaxBodyBytesMiddleware(int64(handlerEnv.MaxBodyBytes)))
+	}
+	if handlerEnv.CORSAllowedOrigins != "" {
+		corsMiddleware, err := newDefaultCORSMiddleware(handlerEnv)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+		} else {
+			chain = chain.Append(corsMiddleware)
+		}
+	}
+	if handlerEnv.RateLimitPerMin != 0 {
+		rateLimitOptions := RateLimitOptions{
+			Rate:  float64(handlerEnv.RateLimitPerMin) / 60,
+			Burst: int(handlerEnv.RateLimitBurst),
+		}
+		if handlerEnv.RateLimitHeader != "" {
+			rateLimitOptions.KeyFunc = NewRateLimitKeyHeader(handlerEnv.RateLimitHeader)
+		}
+		chain = chain.Append(NewRateLimitMiddleware(rateLimitOptions))
+	}
+	if handlerEnv.Compression {
+		chain = chain.Append(
+			NewCompressionMiddleware(
+				CompressionOptions{
+					MinSize:      int(handlerEnv.CompressionMinSize),
+					ContentTypes: splitList(handlerEnv.CompressionContentTypes),
+				},
+			),
+		)
+	}
+	return chain.Append(NewRecoverMiddleware(RecoverOptions{Format: recoverFormat})), firstErr
+}
+
+func newDefaultCORSMiddleware(handlerEnv HandlerEnv) (Middleware, error) {
+	corsOptions, err := NewCORSOptions(handlerEnv)
+	if err != nil {
+		return nil, err
+	}
+	return NewCORSMiddleware(corsOptions)
+}
@@ -0,0 +1,95 @@
+package pkghttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestChainOrder(t *testing.T) {
+	var order []string
+	newMiddleware := func(name string) Middleware {
+		return func(handler http.Handler) http.Handler {
+			return http.HandlerFunc(
+				func(responseWriter http.ResponseWriter, request *http.Request) {
+					order = append(order, name)
+					handler.ServeHTTP(responseWriter, request)
+				},
+			)
+		}
+	}
+	handler := NewChain(newMiddleware("b")).
+		Prepend(newMiddleware("a")).
+		Append(newMiddleware("c")).
+		Then(http.HandlerFunc(func(http.ResponseWriter, *http.Request) { order = append(order, "handler") }))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	require.Equal(t, []string{"a", "b", "c", "handler"}, order)
+}
+
+func TestDefaultChain(t *testing.T) {
+	var call *Call
+	chain, err := NewDefaultChain(HandlerEnv{})
+	require.NoError(t, err)
+	handler := chain.
+		Append(
+			func(handler http.Handler) http.Handler {
+				return http.HandlerFunc(
+					func(responseWriter http.ResponseWriter, request *http.Request) {
+						call = CallFromContext(request.Context())
+						handler.ServeHTTP(responseWriter, request)
+					},
+				)
+			},
+		).
+		Then(http.HandlerFunc(func(http.ResponseWriter, *http.Request) { panic("oops") }))
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/health", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Nil(t, call)
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/foo?bar=baz", nil))
+	require.Equal(t, http.StatusInternalServerError, recorder.Code)
+	require.NotNil(t, call)
+	require.Equal(t, "/foo", call.Path)
+	require.Equal(t, map[string]string{"bar": "baz"}, call.Query)
+	require.Equal(t, uint32(http.StatusInternalServerError), call.StatusCode)
+	require.Contains(t, call.Error, "panic: oops")
+	require.Len(t, call.RequestId, 32)
+	require.Equal(t, call.RequestId, recorder.Header().Get(RequestIDHeader))
+
+	recorder = httptest.NewRecorder()
+	request := httptest.NewRequest("GET", "/foo", nil)
+	request.Header.Set(RequestIDHeader, "abc-123")
+	handler.ServeHTTP(recorder, request)
+	require.Equal(t, "abc-123", call.RequestId)
+	require.Equal(t, "abc-123", recorder.Header().Get(RequestIDHeader))
+}
+
+func TestDefaultChainInvalid(t *testing.T) {
+	for _, handlerEnv := range []HandlerEnv{
+		{LogSampleRates: "2xx"},
+		{RecoverFormat: "xml"},
+		{CORSAllowedOrigins: "*", CORSAllowCredentials: true},
+	} {
+		_, err := NewDefaultChain(handlerEnv)
+		require.Error(t, err)
+		_, err = NewServer(http.NotFoundHandler(), handlerEnv, ServerOptions{})
+		require.Error(t, err)
+	}
+
+	// NewWrapperHandler logs the error and does not handle CORS
+	handler := NewWrapperHandler(
+		http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}),
+		HandlerEnv{CORSAllowedOrigins: "*", CORSAllowCredentials: true},
+	)
+	request := httptest.NewRequest("GET", "/foo", nil)
+	request.Header.Set("Origin", "https://example.com")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, "", recorder.Header().Get("Access-Control-Allow-Origin"))
+}
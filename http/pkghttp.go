@@ -4,6 +4,8 @@ Package pkghttp defines common functionality for http.
 package pkghttp // import "go.pedge.io/pkg/http"
 
 import (
+	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"net/http"
@@ -12,30 +14,184 @@ import (
 	"text/template"
 	"time"
 
-	"gopkg.in/tylerb/graceful.v1"
-
 	"go.pedge.io/env"
 	"go.pedge.io/lion/proto"
-	"go.pedge.io/pb/go/google/protobuf"
 	"go.pedge.io/pb/go/google/type"
 	"go.pedge.io/pb/go/pb/money"
 	"go.pedge.io/pkg/tmpl"
 )
 
+const (
+	// RequestIDHeader is the header for request IDs.
+	RequestIDHeader = "X-Request-ID"
+	// ContentTypeJSON is the content type for JSON.
+	ContentTypeJSON = "application/json"
+	// ContentTypeProtobuf is the content type for protobuf.
+	ContentTypeProtobuf = "application/x-protobuf"
+	// ContentTypeProblemJSON is the content type for RFC 7807 problem details.
+	ContentTypeProblemJSON = "application/problem+json"
+	// DefaultMaxBodyBytes is the default maximum size of a request body for Decode.
+	DefaultMaxBodyBytes = 4 << 20
+)
+
 var (
 	// ErrRequireHandler is the error returned if handler is not set.
 	ErrRequireHandler = errors.New("pkghttp: handler must be set")
+	// ErrRequestBodyTooLarge is the error returned when reading a request body
+	// that is larger than the limit of NewMaxBodyBytesMiddleware.
+	ErrRequestBodyTooLarge = errors.New("pkghttp: request body too large")
+	// ErrServerAlreadyStarted is the error returned if Serve is called more than once.
+	ErrServerAlreadyStarted = errors.New("pkghttp: server already started")
+
+	// DefaultHealthRegistry is the HealthRegistry used by ListenAndServe.
+	DefaultHealthRegistry = NewHealthRegistry()
 )
 
 // HandlerEnv is the environment for a handler.
 type HandlerEnv struct {
 	// The port to serve on.
+	// Not used if Addresses is set.
 	Port uint16 `env:"PORT,default=8080"`
+	// Addresses is a comma-separated list of addresses to serve on, each one of:
+	//
+	//	host:port       a TCP address, where host may be empty for all interfaces
+	//	unix:path       a Unix domain socket, created with UnixSocketMode
+	//	systemd         all file descriptors passed by systemd socket activation
+	//	systemd:name    the file descriptors passed by systemd with the name from LISTEN_FDNAMES
+	//
+	// If not set, the server serves on Port on all interfaces.
+	Addresses string `env:"HTTP_ADDRESSES"`
+	// UnixSocketMode is the octal permissions of the Unix domain sockets in Addresses.
+	// Default value is 0660.
+	UnixSocketMode string `env:"HTTP_UNIX_SOCKET_MODE,default=0660"`
 	// HealthCheckPath is the path for health checking.
 	// This path will always return 200 for a GET.
 	// Default value is /health.
 	HealthCheckPath string `env:"HTTP_HEALTH_CHECK_PATH,default=/health"`
-	// The time in seconds to shutdown after a SIGINT or SIGTERM.
+	// LivenessPath is the path for the liveness checks of DefaultHealthRegistry.
+	// Returns 200 if all checks pass and 503 otherwise, with a JSON HealthReport.
+	// Default value is /health/live.
+	LivenessPath string `env:"HTTP_LIVENESS_PATH,default=/health/live"`
+	// ReadinessPath is the path for the readiness checks of DefaultHealthRegistry.
+	// Returns 200 if all checks pass and 503 otherwise, with a JSON HealthReport.
+	// Returns 503 once the server begins to shut down.
+	// Default value is /health/ready.
+	ReadinessPath string `env:"HTTP_READINESS_PATH,default=/health/ready"`
+	// MetricsPath is the path to serve Prometheus metrics on.
+	// If not set, metrics are not collected or served.
+	MetricsPath string `env:"HTTP_METRICS_PATH"`
+	// The path of the PEM-encoded TLS certificate file.
+	// If set, TLSKeyFile must be set, and the server serves HTTPS.
+	// The certificate and key are reloaded when the files change.
+	TLSCertFile string `env:"HTTP_TLS_CERT_FILE"`
+	// The path of the PEM-encoded TLS key file.
+	// If set, TLSCertFile must be set.
+	TLSKeyFile string `env:"HTTP_TLS_KEY_FILE"`
+	// The path of the PEM-encoded CA certificates file to verify client certificates with.
+	// If set, clients must present a certificate signed by one of the CAs.
+	TLSClientCAFile string `env:"HTTP_TLS_CLIENT_CA_FILE"`
+	// The minimum TLS version, one of 1.0, 1.1, 1.2, 1.3.
+	// Default value is 1.2.
+	TLSMinVersion string `env:"HTTP_TLS_MIN_VERSION,default=1.2"`
+	// LogRedactHeaders is a comma-separated list of request and response headers
+	// whose values are redacted in the Call log, in addition to Authorization,
+	// Cookie, Proxy-Authorization, and Set-Cookie, which are always redacted.
+	LogRedactHeaders string `env:"HTTP_LOG_REDACT_HEADERS"`
+	// LogRedactFormKeys is a comma-separated list of form and query keys
+	// whose values are redacted in the Call log, in addition to access_token,
+	// api_key, password, secret, and token, which are always redacted.
+	LogRedactFormKeys string `env:"HTTP_LOG_REDACT_FORM_KEYS"`
+	// LogSkipPaths is a comma-separated list of path patterns that are not
+	// logged, see CallLogOptions.SkipPaths.
+	LogSkipPaths string `env:"HTTP_LOG_SKIP_PATHS"`
+	// LogSampleRates is a comma-separated list of status=rate pairs, where status is
+	// either a status code or a class such as 2xx, and rate is between 0 and 1,
+	// for example 2xx=0.01,404=0.1.
+	// If not set, all calls are logged.
+	LogSampleRates string `env:"HTTP_LOG_SAMPLE_RATES"`
+	// CORSAllowedOrigins is a comma-separated list of origins allowed to make
+	// cross-origin requests, where * allows all origins and an origin such as
+	// https://*.example.com allows all subdomains.
+	// If not set, CORS is not handled.
+	CORSAllowedOrigins string `env:"HTTP_CORS_ALLOWED_ORIGINS"`
+	// CORSAllowedMethods is a comma-separated list of methods allowed for cross-origin requests.
+	// Default value is GET,HEAD,POST.
+	CORSAllowedMethods string `env:"HTTP_CORS_ALLOWED_METHODS"`
+	// CORSAllowedHeaders is a comma-separated list of request headers allowed for
+	// cross-origin requests, where * allows all headers.
+	// Default value is Accept,Accept-Language,Content-Language,Content-Type,X-Request-ID.
+	CORSAllowedHeaders string `env:"HTTP_CORS_ALLOWED_HEADERS"`
+	// CORSExposedHeaders is a comma-separated list of response headers exposed to cross-origin requests.
+	CORSExposedHeaders string `env:"HTTP_CORS_EXPOSED_HEADERS"`
+	// CORSAllowCredentials allows cross-origin requests with credentials such as cookies.
+	// Cannot be set if CORSAllowedOrigins is *.
+	CORSAllowCredentials bool `env:"HTTP_CORS_ALLOW_CREDENTIALS"`
+	// CORSMaxAgeSec is the time in seconds that the result of a preflight request can be cached.
+	// If not set, no max age is sent.
+	CORSMaxAgeSec uint64 `env:"HTTP_CORS_MAX_AGE_SEC"`
+	// RateLimitPerMin is the number of requests per minute allowed for each client.
+	// Clients are identified by IP, or by RateLimitHeader if set.
+	// If not set, requests are not rate limited.
+	RateLimitPerMin uint64 `env:"HTTP_RATE_LIMIT_PER_MIN"`
+	// RateLimitBurst is the number of requests a client can make at once.
+	// Default value is the number of requests allowed per second, rounded up.
+	RateLimitBurst uint64 `env:"HTTP_RATE_LIMIT_BURST"`
+	// RateLimitHeader is the header to identify clients by for rate limiting,
+	// such as an API key header. Requests without the header are identified by IP.
+	// As clients can send any value, and so get a new limit for every request,
+	// this must only be set behind a trusted proxy that sets or validates the header.
+	RateLimitHeader string `env:"HTTP_RATE_LIMIT_HEADER"`
+	// Compression enables gzip and deflate compression of responses.
+	Compression bool `env:"HTTP_COMPRESSION"`
+	// CompressionMinSize is the minimum size in bytes of a response to compress.
+	// Default value is 1024.
+	CompressionMinSize uint64 `env:"HTTP_COMPRESSION_MIN_SIZE,default=1024"`
+	// CompressionContentTypes is a comma-separated list of content types to compress,
+	// where a content type ending in /* matches all subtypes, for example text/*,application/json.
+	// If not set, the default of CompressionOptions is used.
+	CompressionContentTypes string `env:"HTTP_COMPRESSION_CONTENT_TYPES"`
+	// RecoverFormat is the format of the response body for a recovered panic,
+	// one of json, html.
+	// Default value is json.
+	RecoverFormat string `env:"HTTP_RECOVER_FORMAT,default=json"`
+	// DebugPort is the port to serve the debug handler on, see NewDebugHandler.
+	// Must be different from Port, and should not be exposed publicly.
+	// If not set, the debug handler is not served.
+	DebugPort uint16 `env:"DEBUG_PORT"`
+	// DebugHost is the host to serve the debug handler on, such as 0.0.0.0 for all interfaces.
+	// Default value is 127.0.0.1, so that the debug handler is not reachable from other hosts.
+	DebugHost string `env:"DEBUG_HOST,default=127.0.0.1"`
+	// ReadTimeoutSec is the time in seconds to read a request, including the body.
+	// Default value is 30.
+	ReadTimeoutSec uint64 `env:"HTTP_READ_TIMEOUT_SEC,default=30"`
+	// ReadHeaderTimeoutSec is the time in seconds to read the headers of a request.
+	// A request that times out while its headers are read never reaches the handler,
+	// and is logged as a Call with only LimitExceeded and Error set.
+	// Default value is 10.
+	ReadHeaderTimeoutSec uint64 `env:"HTTP_READ_HEADER_TIMEOUT_SEC,default=10"`
+	// WriteTimeoutSec is the time in seconds from the end of reading the headers
+	// of a request to the end of writing the response. If the handler returns
+	// after the timeout, LimitExceeded is set on the Call.
+	// Default value is 60.
+	WriteTimeoutSec uint64 `env:"HTTP_WRITE_TIMEOUT_SEC,default=60"`
+	// IdleTimeoutSec is the time in seconds to wait for the next request on a keep-alive connection.
+	// Default value is 120.
+	IdleTimeoutSec uint64 `env:"HTTP_IDLE_TIMEOUT_SEC,default=120"`
+	// MaxHeaderBytes is the maximum size of the headers of a request.
+	// A request with larger headers is rejected with a 431 before it reaches the handler,
+	// and is logged as a Call with only StatusCode, LimitExceeded, and Error set.
+	// Default value is 1048576.
+	MaxHeaderBytes uint64 `env:"HTTP_MAX_HEADER_BYTES,default=1048576"`
+	// MaxBodyBytes is the maximum size of the body of a request, see NewMaxBodyBytesMiddleware.
+	// Default value is 10485760.
+	MaxBodyBytes uint64 `env:"HTTP_MAX_BODY_BYTES,default=10485760"`
+	// PreStopDelaySec is the time in seconds to wait after readiness starts failing
+	// before no longer accepting connections, so that load balancers can stop
+	// sending requests to the server. A SIGINT or SIGTERM received while waiting
+	// ends the wait.
+	PreStopDelaySec uint64 `env:"HTTP_PRE_STOP_DELAY_SEC"`
+	// The time in seconds to wait for the requests in flight to finish when shutting down,
+	// and again for the shutdown hooks to run.
 	// Default value is 10.
 	ShutdownTimeoutSec uint64 `env:"HTTP_SHUTDOWN_TIMEOUT_SEC,default=10"`
 }
@@ -49,58 +205,524 @@ func GetHandlerEnv() (HandlerEnv, error) {
 	return handlerEnv, nil
 }
 
+// Middleware wraps a http.Handler with additional behavior.
+type Middleware func(http.Handler) http.Handler
+
+// Chain is an ordered list of Middlewares.
+//
+// The first Middleware is the outermost, ie it sees a request first.
+type Chain []Middleware
+
+// NewChain returns a new Chain.
+func NewChain(middlewares ...Middleware) Chain {
+	return Chain(middlewares)
+}
+
+// NewDefaultChain returns the Chain used by ListenAndServe.
+//
+// This is, in order, NewHealthCheckMiddleware, NewHealthRegistryMiddleware for
+// DefaultHealthRegistry, NewMetricsMiddleware if handlerEnv.MetricsPath is set,
+// NewCallLogMiddleware, NewRequestIDMiddleware, NewMaxBodyBytesMiddleware if
+// handlerEnv.MaxBodyBytes is set, NewCORSMiddleware if
+// handlerEnv.CORSAllowedOrigins is set, NewRateLimitMiddleware if
+// handlerEnv.RateLimitPerMin is set, NewCompressionMiddleware if handlerEnv.Compression
+// is set, and NewRecoverMiddleware.
+//
+// Returns error if the log fields, CORS fields, or recover format of handlerEnv are invalid.
+func NewDefaultChain(handlerEnv HandlerEnv) (Chain, error) {
+	chain, err := newDefaultChain(handlerEnv)
+	if err != nil {
+		return nil, err
+	}
+	return chain, nil
+}
+
+// Append returns a new Chain with the middlewares added after the Middlewares in c.
+func (c Chain) Append(middlewares ...Middleware) Chain {
+	return append(append(Chain{}, c...), middlewares...)
+}
+
+// Prepend returns a new Chain with the middlewares added before the Middlewares in c.
+func (c Chain) Prepend(middlewares ...Middleware) Chain {
+	return append(append(Chain{}, middlewares...), c...)
+}
+
+// Then returns handler wrapped by every Middleware in c.
+func (c Chain) Then(handler http.Handler) http.Handler {
+	return chainThen(c, handler)
+}
+
+// NewHealthCheckMiddleware returns a Middleware that returns 200 for any request to healthCheckPath.
+//
+// If healthCheckPath is empty, /health is used.
+func NewHealthCheckMiddleware(healthCheckPath string) Middleware {
+	return newHealthCheckMiddleware(healthCheckPath)
+}
+
+// NewMaxBodyBytesMiddleware returns a Middleware that limits the body of a request to maxBytes.
+//
+// A request with a Content-Length larger than maxBytes is rejected with a 413. Otherwise,
+// reading more than maxBytes of the body returns ErrRequestBodyTooLarge. In both cases,
+// and if reading the body times out, the reason is set as LimitExceeded on the Call.
+func NewMaxBodyBytesMiddleware(maxBytes int64) Middleware {
+	return newMaxBodyBytesMiddleware(maxBytes)
+}
+
+// CallLogOptions are options for a new call log Middleware.
+type CallLogOptions struct {
+	// The request and response headers whose values are replaced with REDACTED,
+	// in addition to Authorization, Cookie, Proxy-Authorization, and Set-Cookie,
+	// which are always redacted. Matched case-insensitively.
+	RedactHeaders []string
+	// The form and query keys whose values are replaced with REDACTED, in addition
+	// to access_token, api_key, password, secret, and token, which are always redacted.
+	// Matched case-insensitively.
+	RedactFormKeys []string
+	// The path patterns that are not logged.
+	// Patterns are matched with path.Match, except that a pattern ending
+	// in /** matches every path under the prefix before the /**.
+	SkipPaths []string
+	// The rate at which calls are logged, by either status code such as 404, or
+	// status class such as 2xx. A status code takes precedence over its class.
+	// Statuses that are not set, and calls with an error, are always logged.
+	SampleRates map[string]float64
+}
+
+// NewCallLogOptions returns the CallLogOptions for the log fields of handlerEnv.
+//
+// Returns error if any of the fields are invalid.
+func NewCallLogOptions(handlerEnv HandlerEnv) (CallLogOptions, error) {
+	return newCallLogOptions(handlerEnv)
+}
+
+// NewCallLogMiddleware returns a Middleware that logs a Call for every request.
+//
+// Middlewares and handlers after this Middleware can get the Call with CallFromContext.
+// If writing the response times out, or the handler returns after the write timeout
+// of a Server, LimitExceeded is set on the Call.
+func NewCallLogMiddleware(opts CallLogOptions) Middleware {
+	return newCallLogMiddleware(opts)
+}
+
+// CORSOptions are options for a new CORS Middleware.
+type CORSOptions struct {
+	// The origins allowed to make cross-origin requests, where * allows all
+	// origins and an origin such as https://*.example.com allows all subdomains.
+	AllowedOrigins []string
+	// The methods allowed for cross-origin requests.
+	// If empty, GET, HEAD, and POST are used.
+	AllowedMethods []string
+	// The request headers allowed for cross-origin requests, where * allows all headers.
+	// If empty, Accept, Accept-Language, Content-Language, Content-Type, and
+	// RequestIDHeader are used.
+	AllowedHeaders []string
+	// The response headers exposed to cross-origin requests.
+	ExposedHeaders []string
+	// Allow cross-origin requests with credentials such as cookies.
+	// Cannot be set if AllowedOrigins contains *.
+	AllowCredentials bool
+	// The time that the result of a preflight request can be cached.
+	// If 0, no max age is sent.
+	MaxAge time.Duration
+}
+
+// NewCORSOptions returns the CORSOptions for the CORS fields of handlerEnv.
+//
+// Returns error if any of the fields are invalid.
+func NewCORSOptions(handlerEnv HandlerEnv) (CORSOptions, error) {
+	return newCORSOptions(handlerEnv)
+}
+
+// NewCORSMiddleware returns a Middleware that handles cross-origin requests.
+//
+// Preflight requests are answered with a 204 if allowed, and a 403 otherwise.
+// Other requests from an origin that is not allowed are served without CORS headers.
+// In both cases, the reason for the rejection is set as CorsRejection on the Call.
+// Every response has Vary: Origin, including those to requests without an Origin.
+//
+// Returns error if opts allows all origins with credentials, or has an invalid wildcard origin.
+func NewCORSMiddleware(opts CORSOptions) (Middleware, error) {
+	return newCORSMiddleware(opts)
+}
+
+// RateLimitKeyFunc returns the key of the client that sent request for rate limiting,
+// or "" if the request should not be rate limited.
+type RateLimitKeyFunc func(request *http.Request) string
+
+// RateLimitKeyIP is a RateLimitKeyFunc that identifies clients by the IP of the remote address.
+//
+// For servers behind a proxy, this is the IP of the proxy, use a RateLimitKeyFunc
+// that reads the header set by the proxy instead.
+func RateLimitKeyIP(request *http.Request) string {
+	return rateLimitKeyIP(request)
+}
+
+// NewRateLimitKeyHeader returns a RateLimitKeyFunc that identifies clients by the value
+// of the given header, such as an API key header, or by RateLimitKeyIP if the header is not set.
+//
+// The value of the header is not validated, so a client can get a new limit for every
+// request by sending a different value. This must only be used behind a trusted proxy
+// that sets the header, or overwrites it with a validated value.
+func NewRateLimitKeyHeader(header string) RateLimitKeyFunc {
+	return newRateLimitKeyHeader(header)
+}
+
+// RateLimitOptions are options for a new rate limit Middleware.
+type RateLimitOptions struct {
+	// The number of requests per second allowed for each client.
+	Rate float64
+	// The number of requests a client can make at once.
+	// If 0, Rate rounded up is used, with a minimum of 1.
+	Burst int
+	// The function to identify clients by.
+	// If nil, RateLimitKeyIP is used.
+	KeyFunc RateLimitKeyFunc
+}
+
+// NewRateLimitMiddleware returns a Middleware that rate limits clients with a token bucket
+// per client, refilled at the rate of opts.Rate up to opts.Burst.
+//
+// Requests over the limit get a 429 with a Retry-After header and a ContentTypeProblemJSON body,
+// and RateLimited is set on the Call.
+func NewRateLimitMiddleware(opts RateLimitOptions) Middleware {
+	return newRateLimitMiddleware(opts)
+}
+
+// CompressionOptions are options for a new compression Middleware.
+type CompressionOptions struct {
+	// The compression level, from compress/flate.
+	// If 0, the default compression level is used.
+	Level int
+	// The minimum size in bytes of a response to compress.
+	// If 0, 1024 is used.
+	MinSize int
+	// The content types to compress, where a content type ending in /* matches all subtypes.
+	// If empty, text/*, application/javascript, application/json, application/problem+json,
+	// application/x-javascript, application/xml, and image/svg+xml are used.
+	ContentTypes []string
+}
+
+// NewCompressionMiddleware returns a Middleware that compresses responses with gzip or
+// deflate, as negotiated from the Accept-Encoding header of the request.
+//
+// A response is compressed if it is at least opts.MinSize bytes or is flushed, its
+// content type is one of opts.ContentTypes, and it does not already have a Content-Encoding.
+// The response writer passed to the handler implements http.Flusher, http.Hijacker,
+// and http.CloseNotifier if and only if the underlying response writer does.
+func NewCompressionMiddleware(opts CompressionOptions) Middleware {
+	return newCompressionMiddleware(opts)
+}
+
+// RecoverFormat is the format of the response body for a recovered panic.
+type RecoverFormat string
+
+const (
+	// RecoverFormatJSON writes a JSON object with the error, incident ID, and request ID.
+	RecoverFormatJSON RecoverFormat = "json"
+	// RecoverFormatHTML writes an HTML page with the incident ID and request ID.
+	RecoverFormatHTML RecoverFormat = "html"
+)
+
+// Panic is a panic recovered while serving a request.
+type Panic struct {
+	// IncidentID is unique to the panic, and is both written in the response
+	// and logged, so that an incident reported by a client can be found in the logs.
+	IncidentID string
+	// Value is the value passed to panic.
+	Value interface{}
+	// Stack is the full stack of the panicking goroutine.
+	Stack []byte
+}
+
+// RecoverHandler writes the response for a recovered panic.
+type RecoverHandler func(responseWriter http.ResponseWriter, request *http.Request, p *Panic)
+
+// RecoverOptions are options for a new recover Middleware.
+type RecoverOptions struct {
+	// The format of the response body.
+	// Default is RecoverFormatJSON.
+	Format RecoverFormat
+	// If set, writes the response instead of Format.
+	Handler RecoverHandler
+}
+
+// NewRecoverMiddleware returns a Middleware that recovers from panics, writes a 500,
+// and sets the panic, full stack, and incident ID on the Call.
+//
+// If the response has already started when the panic happens, nothing is written,
+// and the connection is aborted with http.ErrAbortHandler so that the client does not
+// take the partial response as complete. Panics with http.ErrAbortHandler are not recovered.
+func NewRecoverMiddleware(opts RecoverOptions) Middleware {
+	return newRecoverMiddleware(opts)
+}
+
+// NewRequestIDMiddleware returns a Middleware that reads the request ID from the
+// RequestIDHeader of a request, or generates a new request ID if there is none.
+//
+// The request ID is added to the request context, see RequestIDFromContext,
+// set as the RequestIDHeader of the response, and set on the Call.
+// A request ID that is longer than 128 characters or has characters that are
+// not printable ASCII is replaced with a generated request ID.
+func NewRequestIDMiddleware() Middleware {
+	return newRequestIDMiddleware()
+}
+
+// RequestIDFromContext returns the request ID for the request with the given context,
+// or "" if the request did not go through a NewRequestIDMiddleware.
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// HealthCheck checks the health of a dependency, such as a database.
+//
+// The context is cancelled when the timeout of the check expires.
+type HealthCheck func(ctx context.Context) error
+
+// HealthRegistry is a registry of named health checks.
+type HealthRegistry interface {
+	// RegisterLiveness registers a check that is run for liveness.
+	// If timeout is 0, 5s is used.
+	RegisterLiveness(name string, timeout time.Duration, check HealthCheck)
+	// RegisterReadiness registers a check that is run for readiness.
+	// If timeout is 0, 5s is used.
+	RegisterReadiness(name string, timeout time.Duration, check HealthCheck)
+	// Liveness runs the liveness checks concurrently.
+	Liveness(ctx context.Context) *HealthReport
+	// Readiness runs the readiness checks concurrently.
+	// Fails without running the checks once SetShuttingDown is called.
+	Readiness(ctx context.Context) *HealthReport
+	// SetShuttingDown makes readiness fail, so that load balancers stop
+	// sending requests while the server drains.
+	SetShuttingDown()
+	// ClearShuttingDown makes readiness run the checks again after SetShuttingDown,
+	// such as once the server that was shutting down has finished.
+	ClearShuttingDown()
+}
+
+// NewHealthRegistry returns a new HealthRegistry.
+func NewHealthRegistry() HealthRegistry {
+	return newHealthRegistry()
+}
+
+// HealthReport is the result of running health checks.
+type HealthReport struct {
+	// Either ok or fail.
+	Status       string               `json:"status"`
+	ShuttingDown bool                 `json:"shutting_down,omitempty"`
+	Checks       []*HealthCheckResult `json:"checks,omitempty"`
+}
+
+// HealthCheckResult is the result of a single health check.
+type HealthCheckResult struct {
+	Name string `json:"name"`
+	// Either ok or fail.
+	Status         string  `json:"status"`
+	LatencySeconds float64 `json:"latency_seconds"`
+	Error          string  `json:"error,omitempty"`
+}
+
+// NewHealthRegistryMiddleware returns a Middleware that serves the liveness and
+// readiness checks of registry on livenessPath and readinessPath.
+//
+// If livenessPath or readinessPath is empty, /health/live or /health/ready is used.
+func NewHealthRegistryMiddleware(registry HealthRegistry, livenessPath string, readinessPath string) Middleware {
+	return newHealthRegistryMiddleware(registry, livenessPath, readinessPath)
+}
+
+// MetricsOptions are options for a new metrics Middleware.
+type MetricsOptions struct {
+	// The path to serve metrics on.
+	// Default value is /metrics.
+	Path string
+	// RouteFunc returns the route label for a request.
+	// The number of distinct routes must be bounded, as every route is kept in memory,
+	// so routes with parameters should be mapped to a pattern such as /users/:id,
+	// and unknown paths to a single route.
+	// If not set, every request has the route *.
+	RouteFunc func(*http.Request) string
+	// The upper bounds of the request latency histogram buckets, in seconds.
+	// If not set, the Prometheus default buckets from 0.005 to 10 are used.
+	Buckets []float64
+}
+
+// NewMetricsMiddleware returns a Middleware that collects request metrics, and serves
+// them in the Prometheus text format on MetricsOptions.Path.
+//
+// The metrics are http_requests_total and http_request_duration_seconds labeled by
+// method, route and status, and http_requests_in_flight labeled by method and route.
+// Methods other than the standard methods are labeled OTHER. The status and duration
+// are those of the Call, see CallFromContext.
+func NewMetricsMiddleware(opts MetricsOptions) Middleware {
+	return newMetricsMiddleware(opts)
+}
+
+// CallFromContext returns the Call that will be logged for the request with the
+// given context, or nil if the request is not logged by a NewCallLogMiddleware.
+//
+// Fields set on the Call are kept, the call log Middleware only sets the
+// fields that are not set when the request finishes.
+func CallFromContext(ctx context.Context) *Call {
+	return getCall(ctx)
+}
+
+// NewTLSConfig returns a new tls.Config for the TLS fields of handlerEnv,
+// or nil if handlerEnv.TLSCertFile and handlerEnv.TLSKeyFile are not set.
+func NewTLSConfig(handlerEnv HandlerEnv) (*tls.Config, error) {
+	return newTLSConfig(handlerEnv)
+}
+
 // NewWrapperHandler returns a new wrapper handler.
+//
+// This is the handler wrapped by the Chain from NewDefaultChain. If handlerEnv is
+// invalid, an InvalidHandlerEnv is logged, invalid log fields and recover format are
+// replaced by their defaults, and CORS is not handled if the CORS fields are invalid.
+// Use NewDefaultChain to get the error instead.
 func NewWrapperHandler(delegate http.Handler, handlerEnv HandlerEnv) http.Handler {
-	return newWrapperHandler(delegate, handlerEnv)
+	chain, err := newDefaultChain(handlerEnv)
+	if err != nil {
+		protolion.Error(
+			&InvalidHandlerEnv{
+				Error: err.Error(),
+			},
+		)
+	}
+	return chain.Then(delegate)
 }
 
 // ListenAndServe is the equivalent to http's method.
 //
-// Intercepts requests and responses, handles SIGINT and SIGTERM.
+// Intercepts requests and responses, handles SIGINT and SIGTERM, see Server.
 // When this returns, any errors will have been logged.
 // If the server starts, this will block until the server stops.
 //
-// Uses a wrapper handler.
+// Uses the Chain from NewDefaultChain.
 func ListenAndServe(handler http.Handler, handlerEnv HandlerEnv) error {
-	if handler == nil {
-		return handleErrorBeforeStart(ErrRequireHandler)
-	}
-	if handlerEnv.Port == 0 {
-		handlerEnv.Port = 8080
-	}
-	if handlerEnv.HealthCheckPath == "" {
-		handlerEnv.HealthCheckPath = "/health"
-	}
-	if handlerEnv.ShutdownTimeoutSec == 0 {
-		handlerEnv.ShutdownTimeoutSec = 10
-	}
-	server := &graceful.Server{
-		Timeout: time.Duration(handlerEnv.ShutdownTimeoutSec) * time.Second,
-		Server: &http.Server{
-			Addr: fmt.Sprintf(":%d", handlerEnv.Port),
-			Handler: NewWrapperHandler(
-				handler,
-				handlerEnv,
-			),
-		},
-	}
-	protolion.Info(
-		&ServerStarting{
-			Port: uint32(handlerEnv.Port),
-		},
-	)
-	start := time.Now()
-	err := server.ListenAndServe()
-	serverFinished := &ServerFinished{
-		Duration: google_protobuf.DurationToProto(time.Since(start)),
-	}
+	return ListenAndServeWithChain(handler, handlerEnv, nil)
+}
+
+// ListenAndServeWithChain is ListenAndServe with the given Chain instead of the default Chain.
+//
+// If chain is nil, the Chain from NewDefaultChain is used.
+func ListenAndServeWithChain(handler http.Handler, handlerEnv HandlerEnv, chain Chain) error {
+	server, err := NewServer(handler, handlerEnv, ServerOptions{Chain: chain})
 	if err != nil {
-		serverFinished.Error = err.Error()
-		protolion.Error(serverFinished)
-		return err
+		return handleErrorBeforeStart(err)
 	}
-	protolion.Info(serverFinished)
-	return nil
+	return server.Serve(context.Background())
+}
+
+// ShutdownHook is run when a Server shuts down, after the requests in flight have finished.
+//
+// The context is cancelled when the shutdown timeout expires.
+type ShutdownHook func(ctx context.Context) error
+
+// Server is an http server with a graceful shutdown.
+type Server interface {
+	// OnShutdown registers a ShutdownHook, such as to flush metrics or close
+	// database connections. Hooks are run in the order they are registered.
+	OnShutdown(name string, shutdownHook ShutdownHook)
+	// Serve serves until the server fails, ctx is done, or a SIGINT or SIGTERM is received,
+	// and then shuts down.
+	//
+	// Shutting down fails readiness on DefaultHealthRegistry, waits for
+	// the pre-stop delay so that load balancers stop sending requests,
+	// waits up to the shutdown timeout for the requests in flight to finish,
+	// and then runs the shutdown hooks with the shutdown timeout. A SIGINT or
+	// SIGTERM received during the pre-stop delay ends the delay. Readiness
+	// on DefaultHealthRegistry is restored once the server has finished.
+	// The shutdown hooks are run even if the server fails to start.
+	//
+	// The server serves on every address of the HandlerEnv, with TLS on all of them
+	// if TLS is configured. A Unix domain socket left behind by a previous server
+	// is replaced.
+	//
+	// If the DebugPort of the HandlerEnv is set, the debug handler is served on
+	// the DebugHost and DebugPort with the same lifecycle, and is shut down last.
+	//
+	// A ServerFinished with the reason the server stopped is logged.
+	// Returns the error the server failed with, or the first error while shutting down.
+	// Can only be called once.
+	Serve(ctx context.Context) error
+}
+
+// TransportOptions are options for a new Transport.
+type TransportOptions struct {
+	// The RoundTripper to send requests with.
+	// If nil, http.DefaultTransport is used.
+	Base http.RoundTripper
+	// The timeout for each attempt of a request, until the response body is closed.
+	// If 0, there is no timeout.
+	Timeout time.Duration
+	// The timeouts by host, either host:port or host, that take precedence over Timeout.
+	HostTimeouts map[string]time.Duration
+	// The maximum number of attempts for an idempotent request, including the first.
+	// If 0, 3 is used.
+	MaxAttempts int
+	// The backoff before the second attempt, which doubles for every attempt after.
+	// If 0, 100ms is used.
+	InitialBackoff time.Duration
+	// The maximum backoff.
+	// If 0, 2s is used.
+	MaxBackoff time.Duration
+	// The request and response headers whose values are replaced with REDACTED in the log,
+	// in addition to Authorization, Cookie, Proxy-Authorization, and Set-Cookie.
+	RedactHeaders []string
+	// The query keys whose values are replaced with REDACTED in the log, in addition
+	// to access_token, api_key, password, secret, and token.
+	RedactQueryKeys []string
+}
+
+// NewTransport returns a new http.RoundTripper that logs a ClientCall for every attempt of a request.
+//
+// If the request has no RequestIDHeader, the request ID from the context of the request
+// is sent, see RequestIDFromContext, so that the request ID of an incoming request
+// is propagated to the outgoing requests made while handling it.
+//
+// Requests with an idempotent method, or with an Idempotency-Key header, are retried
+// on errors and on 502, 503, and 504, with exponential backoff and jitter, if the
+// body of the request is empty or can be sent again with GetBody.
+func NewTransport(opts TransportOptions) http.RoundTripper {
+	return newTransport(opts)
+}
+
+// DebugOptions are options for a new debug handler.
+type DebugOptions struct {
+	// Vars returns the variables of the application, such as counters, which are
+	// served on /debug/vars along with cmdline and memstats, which take precedence.
+	// Called on every request to /debug/vars, so it must be safe for concurrent use.
+	Vars func() map[string]interface{}
+}
+
+// NewDebugHandler returns a new handler for debugging, which serves:
+//
+//	/debug/pprof/      the profiles of runtime/pprof, a CPU profile on profile, and an execution trace on trace
+//	/debug/vars        the command line, memory statistics, and DebugOptions.Vars as JSON
+//	/debug/buildinfo   the go version, main module, and dependencies as JSON
+//	/debug/loglevel    the lion log level, which is set by a PUT or POST with the form value level
+//	/debug/goroutines  the stacks of all goroutines
+//
+// This should never be served on a public port. Unlike net/http/pprof and
+// expvar, nothing is registered on http.DefaultServeMux, so the variables
+// published with expvar are not served.
+func NewDebugHandler(opts DebugOptions) http.Handler {
+	return newDebugHandler(opts)
+}
+
+// ServerOptions are options for a new Server.
+type ServerOptions struct {
+	// The Chain to wrap the handler with.
+	// If nil, the Chain from NewDefaultChain is used.
+	Chain Chain
+	// The options for the debug handler served if the DebugPort of the HandlerEnv is set.
+	Debug DebugOptions
+}
+
+// NewServer returns a new Server for handler.
+//
+// Returns error if handler is nil or handlerEnv is invalid.
+func NewServer(handler http.Handler, handlerEnv HandlerEnv, opts ServerOptions) (Server, error) {
+	return newServer(handler, handlerEnv, opts)
 }
 
 // GetAndListenAndServe is GetHandlerEnv then ListenAndServe.
@@ -119,10 +741,56 @@ type Templater interface {
 }
 
 // NewTemplater returns a new Templater.
+//
+// Templates are executed with text/template, so nothing is escaped. Use
+// NewHTMLTemplater for HTML pages.
 func NewTemplater(baseDirPath string) Templater {
 	return newTemplater(pkgtmpl.NewTemplater(baseDirPath))
 }
 
+// HTMLTemplater is a templater for http responses that executes templates with html/template.
+//
+// HTMLTemplater has the methods of Templater, but WithFuncs returns an HTMLTemplater so
+// that it can be chained with WithLayout. As a result, an HTMLTemplater is not a Templater.
+type HTMLTemplater interface {
+	// WithFuncs returns a copy of the HTMLTemplater with funcMap added to its functions.
+	WithFuncs(funcMap template.FuncMap) HTMLTemplater
+	Execute(responseWriter http.ResponseWriter, name string, data interface{})
+	// WithLayout returns a copy of the HTMLTemplater with the given layout,
+	// or with no layout if layout is empty.
+	WithLayout(layout string) HTMLTemplater
+}
+
+// HTMLTemplaterOptions are options for a new HTMLTemplater.
+type HTMLTemplaterOptions struct {
+	// The path of the layout relative to the base directory, such as layouts/base.html.
+	// If set, pages are executed by executing the layout, which defines blocks
+	// with {{block "name" .}}default{{end}} that pages override with
+	// {{define "name"}}content{{end}}. Any content of a page outside of a
+	// define is not rendered.
+	// If empty, pages are executed directly.
+	Layout string
+	// The glob of the partials relative to the base directory, which are
+	// available to all pages with {{template "name.html" .}}.
+	// If empty, partials/*.html is used.
+	Partials string
+	// Parse the templates on every execution, for development.
+	NoCache bool
+}
+
+// NewHTMLTemplater returns a new HTMLTemplater.
+//
+// Templates are executed with html/template, so values are escaped according to
+// their context. Templates are referred to by their file name without the directory,
+// so the file names of the layout, partials, and pages must be unique.
+//
+// A page is rendered into a buffer before it is written with the Content-Type
+// text/html; charset=utf-8. If a template fails, a TemplateFailed is logged
+// and a 500 is written with no details of the failure.
+func NewHTMLTemplater(baseDirPath string, opts HTMLTemplaterOptions) HTMLTemplater {
+	return newHTMLTemplater(baseDirPath, opts)
+}
+
 // Error does http.Error on the error if not nil, and returns true if not nil.
 func Error(responseWriter http.ResponseWriter, statusCode int, err error) bool {
 	if err != nil {
@@ -144,6 +812,210 @@ func FileHandlerFunc(filePath string) func(http.ResponseWriter, *http.Request) {
 	}
 }
 
+// FieldError is an error binding a field in Bind.
+type FieldError struct {
+	// The name of the struct field.
+	Field string
+	// The source of the value, one of path, query, form, header.
+	Source string
+	// The key of the value in the source.
+	Key string
+	// The value that could not be parsed, with multiple values joined by commas.
+	// Empty if the value is required and not set.
+	Value string
+	// The error parsing the value.
+	Err error
+}
+
+// Error implements error.
+func (e *FieldError) Error() string {
+	if e.Value == "" {
+		return fmt.Sprintf("%s %s: %s", e.Source, e.Key, e.Err.Error())
+	}
+	return fmt.Sprintf("%s %s: invalid value %q: %s", e.Source, e.Key, e.Value, e.Err.Error())
+}
+
+// BindError is the error returned by Bind if any fields could not be bound.
+type BindError struct {
+	FieldErrors []*FieldError
+}
+
+// Error implements error.
+func (e *BindError) Error() string {
+	fieldErrorStrings := make([]string, len(e.FieldErrors))
+	for i, fieldError := range e.FieldErrors {
+		fieldErrorStrings[i] = fieldError.Error()
+	}
+	return fmt.Sprintf("pkghttp: invalid request: %s", strings.Join(fieldErrorStrings, "; "))
+}
+
+// WithPathValues returns a shallow copy of request with the given path values
+// added, for use by Bind. This is meant to be called by routers.
+func WithPathValues(request *http.Request, pathValues map[string]string) *http.Request {
+	return withPathValues(request, pathValues)
+}
+
+// Bind fills the struct pointed to by dst from the values of request.
+//
+// A field is filled from the first of the following tags that has a value:
+//
+//	path:"name"    the path value set with WithPathValues
+//	query:"name"   the URL query
+//	form:"name"    the form, which is parsed if needed, including the URL query
+//	header:"name"  the header
+//
+// Fields that have no value are left as is, unless the field also has the tag
+// required:"true". Fields of embedded structs are filled as well.
+//
+// Supported field types are string, bool, all integer and float types, time.Time,
+// time.Duration, *pbmoney.Money as parsed by ParseMoney, *google_type.Date as parsed
+// by ParseDate, types that implement encoding.TextUnmarshaler, and pointers and
+// slices of these. A slice is filled from all values of the key. time.Time and
+// *google_type.Date are parsed with the layout of the tag layout:"layout" if set,
+// otherwise with time.RFC3339 and 2006-01-02 respectively.
+//
+// Returns a *BindError with an error for every field that could not be filled.
+func Bind(request *http.Request, dst interface{}) error {
+	return bind(request, dst)
+}
+
+// DecodeError is the error returned by Decode and Encode for an invalid request.
+type DecodeError struct {
+	// The status code to respond with, such as 400, 406, 413, or 415.
+	StatusCode int
+	Err        error
+}
+
+// Error implements error.
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("pkghttp: %s", e.Err.Error())
+}
+
+// Unwrap returns the underlying error.
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// DecodeOptions are options for DecodeWithOptions.
+type DecodeOptions struct {
+	// The maximum size of the request body.
+	// If 0, DefaultMaxBodyBytes is used.
+	MaxBodyBytes int64
+	// Do not fail on fields in the request body that are not in dst.
+	AllowUnknownFields bool
+}
+
+// Decode is DecodeWithOptions with the default DecodeOptions.
+func Decode(request *http.Request, dst interface{}) error {
+	return DecodeWithOptions(request, dst, DecodeOptions{})
+}
+
+// DecodeWithOptions decodes the body of request into dst according to the Content-Type of request.
+//
+// If the Content-Type is ContentTypeJSON or not set, the body is decoded with jsonpb if
+// dst is a proto.Message, and with encoding/json otherwise. If the Content-Type is
+// ContentTypeProtobuf, dst must be a proto.Message.
+//
+// Returns a *DecodeError if the request is invalid.
+func DecodeWithOptions(request *http.Request, dst interface{}, opts DecodeOptions) error {
+	return decode(request, dst, opts)
+}
+
+// Encode writes src with the status code, in the content type negotiated from
+// the Accept header of request.
+//
+// If src is a proto.Message, src is encoded as protobuf if the request accepts
+// ContentTypeProtobuf more than ContentTypeJSON, and with jsonpb otherwise. If src is
+// not a proto.Message, src is encoded with encoding/json. If src cannot be encoded, an
+// error response is written with WriteProblem, and the error is returned.
+func Encode(responseWriter http.ResponseWriter, request *http.Request, statusCode int, src interface{}) error {
+	return encode(responseWriter, request, statusCode, src)
+}
+
+// EncodeError is WriteProblem for a response with no request.
+func EncodeError(responseWriter http.ResponseWriter, err error) {
+	writeProblem(responseWriter, nil, err)
+}
+
+// HTTPError is an error with a status code, written as RFC 7807 problem details
+// by WriteProblem.
+type HTTPError struct {
+	// The status code.
+	// If 0, 500 is used.
+	Status int
+	// A URI that identifies the problem type.
+	// If empty, about:blank is used.
+	Type string
+	// A short summary of the problem type.
+	// If empty, the status text of Status is used.
+	Title string
+	// An explanation specific to this occurrence of the problem.
+	Detail string
+	// A URI that identifies this occurrence of the problem.
+	Instance string
+	// Additional members of the problem details.
+	Extensions map[string]interface{}
+	// The underlying error, which is logged but not written.
+	Err error
+}
+
+// NewHTTPError returns a new HTTPError with the status code and detail.
+func NewHTTPError(status int, detail string) *HTTPError {
+	return &HTTPError{
+		Status: status,
+		Detail: detail,
+	}
+}
+
+// Error implements error.
+func (e *HTTPError) Error() string {
+	message := fmt.Sprintf("%d %s", e.status(), e.title())
+	if e.Detail != "" {
+		message = fmt.Sprintf("%s: %s", message, e.Detail)
+	}
+	if e.Err != nil {
+		message = fmt.Sprintf("%s: %s", message, e.Err.Error())
+	}
+	return message
+}
+
+// Unwrap returns the underlying error.
+func (e *HTTPError) Unwrap() error {
+	return e.Err
+}
+
+// ToHTTPError returns the HTTPError for err.
+//
+// A *HTTPError is returned as is. A *DecodeError is mapped to its status code, a *BindError
+// to 400 with the field errors as the errors extension, ErrRequestBodyTooLarge to 413,
+// context.DeadlineExceeded to 504, a not exist error to 404, and a permission error to 403.
+// These are matched with errors.As and errors.Is, so errors that wrap them are mapped the same.
+// Any other error is mapped to 500, and the error is not included in the detail.
+func ToHTTPError(err error) *HTTPError {
+	return toHTTPError(err)
+}
+
+// WriteProblem writes the HTTPError for err, as returned by ToHTTPError, as
+// ContentTypeProblemJSON, and sets err as the error of the Call.
+//
+// If the request has a request ID, it is added as the request_id extension.
+func WriteProblem(responseWriter http.ResponseWriter, request *http.Request, err error) {
+	writeProblem(responseWriter, request, err)
+}
+
+// ErrorHandlerFunc is a handler function that returns an error.
+//
+// If an error is returned, it is written with WriteProblem, so nothing
+// should have been written to the response.
+type ErrorHandlerFunc func(responseWriter http.ResponseWriter, request *http.Request) error
+
+// ServeHTTP implements http.Handler.
+func (f ErrorHandlerFunc) ServeHTTP(responseWriter http.ResponseWriter, request *http.Request) {
+	if err := f(responseWriter, request); err != nil {
+		writeProblem(responseWriter, request, err)
+	}
+}
+
 // QueryGet gets the string by key from the request query, if it exists.
 // Otherwise, returns "".
 func QueryGet(request *http.Request, key string) string {
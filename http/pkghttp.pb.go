@@ -30,6 +30,11 @@ type Call struct {
 	StatusCode     uint32                    `protobuf:"varint,7,opt,name=status_code,json=statusCode" json:"status_code,omitempty"`
 	Duration       *google_protobuf.Duration `protobuf:"bytes,8,opt,name=duration" json:"duration,omitempty"`
 	Error          string                    `protobuf:"bytes,9,opt,name=error" json:"error,omitempty"`
+	RequestId      string                    `protobuf:"bytes,10,opt,name=request_id,json=requestId" json:"request_id,omitempty"`
+	IncidentId     string                    `protobuf:"bytes,11,opt,name=incident_id,json=incidentId" json:"incident_id,omitempty"`
+	RateLimited    bool                      `protobuf:"varint,12,opt,name=rate_limited,json=rateLimited" json:"rate_limited,omitempty"`
+	CorsRejection  string                    `protobuf:"bytes,13,opt,name=cors_rejection,json=corsRejection" json:"cors_rejection,omitempty"`
+	LimitExceeded  string                    `protobuf:"bytes,14,opt,name=limit_exceeded,json=limitExceeded" json:"limit_exceeded,omitempty"`
 }
 
 func (m *Call) Reset()                    { *m = Call{} }
@@ -100,6 +105,41 @@ func (m *Call) GetError() string {
 	return ""
 }
 
+func (m *Call) GetRequestId() string {
+	if m != nil {
+		return m.RequestId
+	}
+	return ""
+}
+
+func (m *Call) GetIncidentId() string {
+	if m != nil {
+		return m.IncidentId
+	}
+	return ""
+}
+
+func (m *Call) GetRateLimited() bool {
+	if m != nil {
+		return m.RateLimited
+	}
+	return false
+}
+
+func (m *Call) GetCorsRejection() string {
+	if m != nil {
+		return m.CorsRejection
+	}
+	return ""
+}
+
+func (m *Call) GetLimitExceeded() string {
+	if m != nil {
+		return m.LimitExceeded
+	}
+	return ""
+}
+
 type ServerCouldNotStart struct {
 	Error string `protobuf:"bytes,1,opt,name=error" json:"error,omitempty"`
 }
@@ -117,7 +157,10 @@ func (m *ServerCouldNotStart) GetError() string {
 }
 
 type ServerStarting struct {
-	Port uint32 `protobuf:"varint,1,opt,name=port" json:"port,omitempty"`
+	Port      uint32   `protobuf:"varint,1,opt,name=port" json:"port,omitempty"`
+	Tls       bool     `protobuf:"varint,2,opt,name=tls" json:"tls,omitempty"`
+	DebugPort uint32   `protobuf:"varint,3,opt,name=debug_port,json=debugPort" json:"debug_port,omitempty"`
+	Addresses []string `protobuf:"bytes,4,rep,name=addresses" json:"addresses,omitempty"`
 }
 
 func (m *ServerStarting) Reset()                    { *m = ServerStarting{} }
@@ -132,9 +175,31 @@ func (m *ServerStarting) GetPort() uint32 {
 	return 0
 }
 
+func (m *ServerStarting) GetTls() bool {
+	if m != nil {
+		return m.Tls
+	}
+	return false
+}
+
+func (m *ServerStarting) GetDebugPort() uint32 {
+	if m != nil {
+		return m.DebugPort
+	}
+	return 0
+}
+
+func (m *ServerStarting) GetAddresses() []string {
+	if m != nil {
+		return m.Addresses
+	}
+	return nil
+}
+
 type ServerFinished struct {
 	Error    string                    `protobuf:"bytes,1,opt,name=error" json:"error,omitempty"`
 	Duration *google_protobuf.Duration `protobuf:"bytes,2,opt,name=duration" json:"duration,omitempty"`
+	Reason   string                    `protobuf:"bytes,3,opt,name=reason" json:"reason,omitempty"`
 }
 
 func (m *ServerFinished) Reset()                    { *m = ServerFinished{} }
@@ -156,42 +221,222 @@ func (m *ServerFinished) GetDuration() *google_protobuf.Duration {
 	return nil
 }
 
+func (m *ServerFinished) GetReason() string {
+	if m != nil {
+		return m.Reason
+	}
+	return ""
+}
+
+type ShutdownHookFinished struct {
+	Name     string                    `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	Error    string                    `protobuf:"bytes,2,opt,name=error" json:"error,omitempty"`
+	Duration *google_protobuf.Duration `protobuf:"bytes,3,opt,name=duration" json:"duration,omitempty"`
+}
+
+func (m *ShutdownHookFinished) Reset()                    { *m = ShutdownHookFinished{} }
+func (m *ShutdownHookFinished) String() string            { return proto.CompactTextString(m) }
+func (*ShutdownHookFinished) ProtoMessage()               {}
+func (*ShutdownHookFinished) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+
+func (m *ShutdownHookFinished) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *ShutdownHookFinished) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+func (m *ShutdownHookFinished) GetDuration() *google_protobuf.Duration {
+	if m != nil {
+		return m.Duration
+	}
+	return nil
+}
+
+type ClientCall struct {
+	Method         string                    `protobuf:"bytes,1,opt,name=method" json:"method,omitempty"`
+	Url            string                    `protobuf:"bytes,2,opt,name=url" json:"url,omitempty"`
+	RequestHeader  map[string]string         `protobuf:"bytes,3,rep,name=request_header,json=requestHeader" json:"request_header,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	ResponseHeader map[string]string         `protobuf:"bytes,4,rep,name=response_header,json=responseHeader" json:"response_header,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	StatusCode     uint32                    `protobuf:"varint,5,opt,name=status_code,json=statusCode" json:"status_code,omitempty"`
+	Duration       *google_protobuf.Duration `protobuf:"bytes,6,opt,name=duration" json:"duration,omitempty"`
+	Error          string                    `protobuf:"bytes,7,opt,name=error" json:"error,omitempty"`
+	RequestId      string                    `protobuf:"bytes,8,opt,name=request_id,json=requestId" json:"request_id,omitempty"`
+	Attempt        uint32                    `protobuf:"varint,9,opt,name=attempt" json:"attempt,omitempty"`
+}
+
+func (m *ClientCall) Reset()                    { *m = ClientCall{} }
+func (m *ClientCall) String() string            { return proto.CompactTextString(m) }
+func (*ClientCall) ProtoMessage()               {}
+func (*ClientCall) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
+
+func (m *ClientCall) GetMethod() string {
+	if m != nil {
+		return m.Method
+	}
+	return ""
+}
+
+func (m *ClientCall) GetUrl() string {
+	if m != nil {
+		return m.Url
+	}
+	return ""
+}
+
+func (m *ClientCall) GetRequestHeader() map[string]string {
+	if m != nil {
+		return m.RequestHeader
+	}
+	return nil
+}
+
+func (m *ClientCall) GetResponseHeader() map[string]string {
+	if m != nil {
+		return m.ResponseHeader
+	}
+	return nil
+}
+
+func (m *ClientCall) GetStatusCode() uint32 {
+	if m != nil {
+		return m.StatusCode
+	}
+	return 0
+}
+
+func (m *ClientCall) GetDuration() *google_protobuf.Duration {
+	if m != nil {
+		return m.Duration
+	}
+	return nil
+}
+
+func (m *ClientCall) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+func (m *ClientCall) GetRequestId() string {
+	if m != nil {
+		return m.RequestId
+	}
+	return ""
+}
+
+func (m *ClientCall) GetAttempt() uint32 {
+	if m != nil {
+		return m.Attempt
+	}
+	return 0
+}
+
+type TemplateFailed struct {
+	Name  string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	Error string `protobuf:"bytes,2,opt,name=error" json:"error,omitempty"`
+}
+
+func (m *TemplateFailed) Reset()                    { *m = TemplateFailed{} }
+func (m *TemplateFailed) String() string            { return proto.CompactTextString(m) }
+func (*TemplateFailed) ProtoMessage()               {}
+func (*TemplateFailed) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6} }
+
+func (m *TemplateFailed) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *TemplateFailed) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+type InvalidHandlerEnv struct {
+	Error string `protobuf:"bytes,1,opt,name=error" json:"error,omitempty"`
+}
+
+func (m *InvalidHandlerEnv) Reset()                    { *m = InvalidHandlerEnv{} }
+func (m *InvalidHandlerEnv) String() string            { return proto.CompactTextString(m) }
+func (*InvalidHandlerEnv) ProtoMessage()               {}
+func (*InvalidHandlerEnv) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{7} }
+
+func (m *InvalidHandlerEnv) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
 func init() {
 	proto.RegisterType((*Call)(nil), "pkghttp.Call")
 	proto.RegisterType((*ServerCouldNotStart)(nil), "pkghttp.ServerCouldNotStart")
 	proto.RegisterType((*ServerStarting)(nil), "pkghttp.ServerStarting")
 	proto.RegisterType((*ServerFinished)(nil), "pkghttp.ServerFinished")
+	proto.RegisterType((*ShutdownHookFinished)(nil), "pkghttp.ShutdownHookFinished")
+	proto.RegisterType((*ClientCall)(nil), "pkghttp.ClientCall")
+	proto.RegisterType((*TemplateFailed)(nil), "pkghttp.TemplateFailed")
+	proto.RegisterType((*InvalidHandlerEnv)(nil), "pkghttp.InvalidHandlerEnv")
 }
 
 func init() { proto.RegisterFile("http/pkghttp.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 417 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x94, 0x92, 0x4f, 0xaf, 0xd2, 0x40,
-	0x14, 0xc5, 0x53, 0xa0, 0xbc, 0xf7, 0x6e, 0x05, 0x5f, 0xe6, 0x19, 0x33, 0xb2, 0x78, 0xd6, 0xc6,
-	0x45, 0x13, 0x93, 0x92, 0x60, 0x4c, 0x88, 0x0b, 0x23, 0x41, 0xd1, 0xb8, 0x30, 0xb1, 0xac, 0x0d,
-	0x29, 0xf6, 0xd2, 0x36, 0x94, 0x4e, 0xb9, 0x9d, 0x92, 0xf0, 0x89, 0xfc, 0x9a, 0xa6, 0x33, 0xad,
-	0xfc, 0x91, 0x85, 0xac, 0x98, 0x7b, 0xe6, 0xdc, 0x1f, 0x93, 0x73, 0x0a, 0x2c, 0x96, 0x32, 0x1f,
-	0xe6, 0xeb, 0xa8, 0xfa, 0xf5, 0x72, 0x12, 0x52, 0xb0, 0x9b, 0x7a, 0x1c, 0x3c, 0x46, 0x42, 0x44,
-	0x29, 0x0e, 0x95, 0xbc, 0x2c, 0x57, 0xc3, 0xb0, 0xa4, 0x40, 0x26, 0x22, 0xd3, 0x46, 0xe7, 0xb7,
-	0x09, 0x9d, 0x69, 0x90, 0xa6, 0xec, 0x39, 0x74, 0x37, 0x28, 0x63, 0x11, 0x72, 0xc3, 0x36, 0xdc,
-	0x3b, 0xbf, 0x9e, 0x18, 0x83, 0x4e, 0x1e, 0xc8, 0x98, 0xb7, 0x94, 0xaa, 0xce, 0xcc, 0x03, 0x73,
-	0x5b, 0x22, 0xed, 0x79, 0xdb, 0x6e, 0xbb, 0xd6, 0x88, 0x7b, 0xcd, 0x9f, 0x57, 0x24, 0xef, 0x47,
-	0x75, 0xf5, 0x39, 0x93, 0xb4, 0xf7, 0xb5, 0x8d, 0x7d, 0x81, 0x3e, 0xe1, 0xb6, 0xc4, 0x42, 0x2e,
-	0x62, 0x0c, 0x42, 0x24, 0xde, 0x51, 0x8b, 0xf6, 0xe9, 0xa2, 0xaf, 0x3d, 0x5f, 0x95, 0x45, 0x03,
-	0x7a, 0x74, 0xac, 0xb1, 0x09, 0x3c, 0x69, 0x40, 0x2b, 0x41, 0x1b, 0x6e, 0x2a, 0xcc, 0xe3, 0x45,
-	0xcc, 0x4c, 0xd0, 0x46, 0x43, 0x2c, 0x3a, 0x28, 0xec, 0x1b, 0x3c, 0x25, 0x2c, 0x72, 0x91, 0x15,
-	0xd8, 0x3c, 0xa6, 0xab, 0x28, 0xaf, 0xce, 0x29, 0xda, 0x74, 0xfc, 0x9a, 0x3e, 0x9d, 0x88, 0xec,
-	0x25, 0x58, 0x85, 0x0c, 0x64, 0x59, 0x2c, 0x7e, 0x89, 0x10, 0xf9, 0x8d, 0x6d, 0xb8, 0x3d, 0x1f,
-	0xb4, 0x34, 0x15, 0x21, 0xb2, 0x77, 0x70, 0xdb, 0xe4, 0xcd, 0x6f, 0x6d, 0xc3, 0xb5, 0x46, 0x2f,
-	0x3c, 0x5d, 0x88, 0xd7, 0x14, 0xe2, 0x7d, 0xaa, 0x0d, 0xfe, 0x5f, 0x2b, 0x7b, 0x06, 0x26, 0x12,
-	0x09, 0xe2, 0x77, 0x2a, 0x74, 0x3d, 0x0c, 0xc6, 0x00, 0x87, 0x68, 0xd9, 0x3d, 0xb4, 0xd7, 0xb8,
-	0xaf, 0xcb, 0xaa, 0x8e, 0xd5, 0xd6, 0x2e, 0x48, 0x4b, 0xac, 0xab, 0xd2, 0xc3, 0xfb, 0xd6, 0xd8,
-	0x18, 0x7c, 0x04, 0xf6, 0x6f, 0xb6, 0x57, 0x11, 0x3e, 0xc0, 0xfd, 0x79, 0xac, 0x57, 0xed, 0x4f,
-	0xe0, 0xe1, 0x42, 0xa0, 0xd7, 0x20, 0x9c, 0x37, 0xf0, 0x30, 0x47, 0xda, 0x21, 0x4d, 0x45, 0x99,
-	0x86, 0xdf, 0x85, 0x9c, 0xcb, 0x80, 0xe4, 0x21, 0x2b, 0xe3, 0x28, 0x2b, 0xe7, 0x35, 0xf4, 0xb5,
-	0x59, 0x99, 0x92, 0x2c, 0x52, 0xdf, 0xb1, 0x20, 0xa9, 0x6c, 0x3d, 0x5f, 0x9d, 0x9d, 0x9f, 0x8d,
-	0x6b, 0x96, 0x64, 0x49, 0x11, 0x63, 0x78, 0x99, 0x76, 0x52, 0x63, 0xeb, 0xbf, 0x6b, 0x5c, 0x76,
-	0xd5, 0xe5, 0xdb, 0x3f, 0x01, 0x00, 0x00, 0xff, 0xff, 0xf0, 0x5e, 0xaf, 0x3a, 0xa1, 0x03, 0x00,
-	0x00,
+	// 703 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0xac, 0x54, 0xdd, 0x4e, 0xdb, 0x4c,
+	0x10, 0x55, 0xfe, 0x93, 0x09, 0xc9, 0xc7, 0xb7, 0x20, 0xe4, 0xa2, 0x96, 0x86, 0x48, 0x6d, 0x53,
+	0x55, 0x32, 0x12, 0x55, 0x25, 0xc4, 0x45, 0x55, 0x94, 0x42, 0xa1, 0x6a, 0x2b, 0x6a, 0x7a, 0x1f,
+	0x2d, 0xd9, 0x21, 0x71, 0xb1, 0xbd, 0x66, 0xbd, 0x0e, 0xe5, 0xba, 0x2f, 0xd9, 0xc7, 0xa9, 0x76,
+	0xd6, 0x26, 0xfc, 0x04, 0x44, 0x2a, 0xae, 0x3c, 0x73, 0xf6, 0xec, 0x99, 0xf1, 0xee, 0xec, 0x01,
+	0x36, 0xd6, 0x3a, 0xde, 0x88, 0x4f, 0x47, 0xe6, 0xeb, 0xc6, 0x4a, 0x6a, 0xc9, 0x6a, 0x59, 0xba,
+	0xba, 0x36, 0x92, 0x72, 0x14, 0xe0, 0x06, 0xc1, 0xc7, 0xe9, 0xc9, 0x86, 0x48, 0x15, 0xd7, 0xbe,
+	0x8c, 0x2c, 0xb1, 0xfb, 0xa7, 0x0a, 0xe5, 0x3e, 0x0f, 0x02, 0xb6, 0x02, 0xd5, 0x10, 0xf5, 0x58,
+	0x0a, 0xa7, 0xd0, 0x29, 0xf4, 0x1a, 0x5e, 0x96, 0x31, 0x06, 0xe5, 0x98, 0xeb, 0xb1, 0x53, 0x24,
+	0x94, 0x62, 0xe6, 0x42, 0xe5, 0x2c, 0x45, 0x75, 0xe1, 0x94, 0x3a, 0xa5, 0x5e, 0x73, 0xd3, 0x71,
+	0xf3, 0xe2, 0x46, 0xc9, 0xfd, 0x6e, 0x96, 0x76, 0x23, 0xad, 0x2e, 0x3c, 0x4b, 0x63, 0x9f, 0xa0,
+	0xad, 0xf0, 0x2c, 0xc5, 0x44, 0x0f, 0xc6, 0xc8, 0x05, 0x2a, 0xa7, 0x4c, 0x1b, 0x3b, 0xd7, 0x37,
+	0x7a, 0x96, 0xb3, 0x4f, 0x14, 0x2b, 0xd0, 0x52, 0x57, 0x31, 0xb6, 0x03, 0x0b, 0xb9, 0xd0, 0x89,
+	0x54, 0xa1, 0x53, 0x21, 0x99, 0xb5, 0x99, 0x32, 0x7b, 0x52, 0x85, 0x56, 0xa4, 0xa9, 0xa6, 0x08,
+	0xfb, 0x0c, 0xff, 0x29, 0x4c, 0x62, 0x19, 0x25, 0x98, 0x37, 0x53, 0x25, 0x95, 0xf5, 0x9b, 0x2a,
+	0x96, 0x74, 0xb5, 0x9b, 0xb6, 0xba, 0x06, 0xb2, 0xe7, 0xd0, 0x4c, 0x34, 0xd7, 0x69, 0x32, 0x18,
+	0x4a, 0x81, 0x4e, 0xad, 0x53, 0xe8, 0xb5, 0x3c, 0xb0, 0x50, 0x5f, 0x0a, 0x64, 0xef, 0xa0, 0x9e,
+	0x9f, 0xb7, 0x53, 0xef, 0x14, 0x7a, 0xcd, 0xcd, 0x27, 0xae, 0xbd, 0x10, 0x37, 0xbf, 0x10, 0xf7,
+	0x63, 0x46, 0xf0, 0x2e, 0xa9, 0x6c, 0x19, 0x2a, 0xa8, 0x94, 0x54, 0x4e, 0x83, 0x0e, 0xdd, 0x26,
+	0xec, 0x19, 0x40, 0xfe, 0xf3, 0xbe, 0x70, 0x80, 0x96, 0x1a, 0x19, 0x72, 0x20, 0x4c, 0x33, 0x7e,
+	0x34, 0xf4, 0x05, 0x46, 0xb4, 0xde, 0xa4, 0x75, 0xc8, 0xa1, 0x03, 0xc1, 0xd6, 0x61, 0x41, 0x71,
+	0x8d, 0x83, 0xc0, 0x0f, 0x7d, 0x8d, 0xc2, 0x59, 0xe8, 0x14, 0x7a, 0x75, 0xaf, 0x69, 0xb0, 0x2f,
+	0x16, 0x62, 0x2f, 0xa0, 0x3d, 0x94, 0x2a, 0x19, 0x28, 0xfc, 0x89, 0x43, 0xea, 0xba, 0x45, 0x32,
+	0x2d, 0x83, 0x7a, 0x39, 0x68, 0x68, 0x24, 0x32, 0xc0, 0x5f, 0x43, 0x44, 0x81, 0xc2, 0x69, 0x5b,
+	0x1a, 0xa1, 0xbb, 0x19, 0xb8, 0xba, 0x05, 0x30, 0x9d, 0x05, 0xb6, 0x08, 0xa5, 0x53, 0xbc, 0xc8,
+	0xa6, 0xcb, 0x84, 0xe6, 0x37, 0x27, 0x3c, 0x48, 0x31, 0x9b, 0x2d, 0x9b, 0x6c, 0x17, 0xb7, 0x0a,
+	0xab, 0x1f, 0x80, 0xdd, 0x1e, 0x86, 0xb9, 0x14, 0xde, 0xc3, 0xe2, 0xcd, 0x39, 0x98, 0x6b, 0xff,
+	0x0e, 0x2c, 0xcd, 0x98, 0x80, 0x79, 0x24, 0xba, 0x6f, 0x60, 0xe9, 0x08, 0xd5, 0x04, 0x55, 0x5f,
+	0xa6, 0x81, 0xf8, 0x26, 0xf5, 0x91, 0xe6, 0x4a, 0x4f, 0x2f, 0xb7, 0x70, 0xe5, 0x72, 0xbb, 0x09,
+	0xb4, 0x2d, 0x99, 0x48, 0x7e, 0x34, 0xa2, 0x87, 0x27, 0x95, 0x26, 0x5a, 0xcb, 0xa3, 0xd8, 0x94,
+	0xd7, 0x41, 0x42, 0xa5, 0xea, 0x9e, 0x09, 0xcd, 0x50, 0x08, 0x3c, 0x4e, 0x47, 0x03, 0xe2, 0x96,
+	0x88, 0xdb, 0x20, 0xe4, 0xd0, 0x6c, 0x78, 0x0a, 0x0d, 0x2e, 0x84, 0xc2, 0x24, 0xc1, 0x84, 0x1e,
+	0x5d, 0xc3, 0x9b, 0x02, 0xdd, 0x34, 0x2f, 0xba, 0xe7, 0x47, 0x7e, 0x32, 0x46, 0x31, 0xbb, 0xb9,
+	0x6b, 0x63, 0x5c, 0x7c, 0xf8, 0x18, 0xaf, 0x40, 0x55, 0x21, 0x4f, 0x64, 0x44, 0x7d, 0x35, 0xbc,
+	0x2c, 0xeb, 0x9e, 0xc3, 0xf2, 0xd1, 0x38, 0xd5, 0x42, 0x9e, 0x47, 0xfb, 0x52, 0x9e, 0x5e, 0x16,
+	0x67, 0x50, 0x8e, 0x78, 0x88, 0x59, 0x6d, 0x8a, 0xa7, 0x0d, 0x15, 0xef, 0x6a, 0xa8, 0xf4, 0xe0,
+	0x86, 0xba, 0xbf, 0xcb, 0x00, 0xfd, 0xc0, 0xc7, 0x48, 0xdf, 0x6b, 0x79, 0x8b, 0x50, 0x4a, 0x55,
+	0x90, 0x55, 0x34, 0x21, 0xfb, 0x7a, 0xcb, 0xc0, 0xac, 0xf3, 0xbd, 0x9c, 0x7a, 0xc6, 0xa5, 0xec,
+	0x03, 0x6c, 0xec, 0xf0, 0xb6, 0x07, 0x59, 0x43, 0x7c, 0x35, 0x5b, 0x6f, 0x6e, 0x27, 0xaa, 0xdc,
+	0xeb, 0x44, 0xd5, 0x7f, 0x70, 0xa2, 0xda, 0xdd, 0x4e, 0x54, 0xbf, 0xe9, 0x44, 0x0e, 0xd4, 0xb8,
+	0xd6, 0x18, 0xc6, 0x9a, 0x0c, 0xac, 0xe5, 0xe5, 0xe9, 0x23, 0xbc, 0xeb, 0x47, 0x78, 0x97, 0xdb,
+	0xd0, 0xfe, 0x81, 0x61, 0x1c, 0x70, 0x8d, 0x7b, 0xdc, 0x0f, 0xe6, 0x19, 0xbc, 0xee, 0x6b, 0xf8,
+	0xff, 0x20, 0x9a, 0xf0, 0xc0, 0x17, 0xfb, 0x3c, 0x12, 0x81, 0x29, 0x3f, 0x99, 0xfd, 0x68, 0x8e,
+	0xab, 0x74, 0xae, 0x6f, 0xff, 0x0e, 0x00, 0x12, 0x3c, 0x4e, 0x0e, 0x9f, 0x07, 0x00, 0x00,
 }
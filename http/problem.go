@@ -0,0 +1,127 @@
+package pkghttp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+)
+
+func (e *HTTPError) title() string {
+	if e.Title != "" {
+		return e.Title
+	}
+	return http.StatusText(e.status())
+}
+
+func (e *HTTPError) status() int {
+	if e.Status == 0 {
+		return http.StatusInternalServerError
+	}
+	return e.Status
+}
+
+func (e *HTTPError) problem() map[string]interface{} {
+	problem := make(map[string]interface{}, len(e.Extensions)+5)
+	for key, value := range e.Extensions {
+		problem[key] = value
+	}
+	problemType := e.Type
+	if problemType == "" {
+		problemType = "about:blank"
+	}
+	problem["type"] = problemType
+	problem["title"] = e.title()
+	problem["status"] = e.status()
+	if e.Detail != "" {
+		problem["detail"] = e.Detail
+	}
+	if e.Instance != "" {
+		problem["instance"] = e.Instance
+	}
+	return problem
+}
+
+func toHTTPError(err error) *HTTPError {
+	var httpError *HTTPError
+	if errors.As(err, &httpError) {
+		return httpError
+	}
+	var decodeError *DecodeError
+	if errors.As(err, &decodeError) {
+		return &HTTPError{
+			Status: decodeError.StatusCode,
+			Detail: decodeError.Err.Error(),
+			Err:    err,
+		}
+	}
+	var bindError *BindError
+	if errors.As(err, &bindError) {
+		fieldErrors := make([]map[string]string, len(bindError.FieldErrors))
+		for i, fieldError := range bindError.FieldErrors {
+			fieldErrors[i] = map[string]string{
+				"field":   fieldError.Field,
+				"source":  fieldError.Source,
+				"key":     fieldError.Key,
+				"message": fieldError.Error(),
+			}
+		}
+		return &HTTPError{
+			Status: http.StatusBadRequest,
+			Detail: "invalid request parameters",
+			Extensions: map[string]interface{}{
+				"errors": fieldErrors,
+			},
+			Err: err,
+		}
+	}
+	switch {
+	case errors.Is(err, ErrRequestBodyTooLarge):
+		return &HTTPError{Status: http.StatusRequestEntityTooLarge, Err: err}
+	case errors.Is(err, context.DeadlineExceeded):
+		return &HTTPError{Status: http.StatusGatewayTimeout, Err: err}
+	case errors.Is(err, os.ErrNotExist):
+		return &HTTPError{Status: http.StatusNotFound, Err: err}
+	case errors.Is(err, os.ErrPermission):
+		return &HTTPError{Status: http.StatusForbidden, Err: err}
+	default:
+		// the error is not meant for clients, so it is only logged
+		return &HTTPError{Status: http.StatusInternalServerError, Err: err}
+	}
+}
+
+func writeProblem(responseWriter http.ResponseWriter, request *http.Request, err error) {
+	if request != nil {
+		if call := getCall(request.Context()); call != nil {
+			call.Error = err.Error()
+		}
+	}
+	writeHTTPError(responseWriter, request, toHTTPError(err))
+}
+
+// writeHTTPError writes httpError without setting it as the error of the Call,
+// for responses that are recorded on the Call otherwise.
+func writeHTTPError(responseWriter http.ResponseWriter, request *http.Request, httpError *HTTPError) {
+	problem := httpError.problem()
+	if request != nil {
+		if requestID := RequestIDFromContext(request.Context()); requestID != "" {
+			if _, ok := problem["request_id"]; !ok {
+				problem["request_id"] = requestID
+			}
+		}
+	}
+	data, marshalErr := json.Marshal(problem)
+	if marshalErr != nil {
+		// the extensions could not be marshalled, write the problem without them
+		data = []byte(fmt.Sprintf(`{"type":"about:blank","title":%q,"status":%d}`, httpError.title(), httpError.status()))
+	}
+	header := responseWriter.Header()
+	header.Set("Content-Type", ContentTypeProblemJSON)
+	header.Set("Content-Length", strconv.Itoa(len(data)))
+	header.Set("X-Content-Type-Options", "nosniff")
+	responseWriter.WriteHeader(httpError.status())
+	_, _ = responseWriter.Write(data)
+}
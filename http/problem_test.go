@@ -0,0 +1,93 @@
+package pkghttp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorHandlerFunc(t *testing.T) {
+	var call *Call
+	var handlerErr error
+	handler := NewChain(
+		NewCallLogMiddleware(CallLogOptions{}),
+		func(handler http.Handler) http.Handler {
+			return http.HandlerFunc(
+				func(responseWriter http.ResponseWriter, request *http.Request) {
+					call = CallFromContext(request.Context())
+					handler.ServeHTTP(responseWriter, request)
+				},
+			)
+		},
+		NewRequestIDMiddleware(),
+	).Then(
+		ErrorHandlerFunc(
+			func(http.ResponseWriter, *http.Request) error {
+				return handlerErr
+			},
+		),
+	)
+	serve := func() map[string]interface{} {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/foo", nil))
+		require.Equal(t, ContentTypeProblemJSON, recorder.Header().Get("Content-Type"))
+		problem := make(map[string]interface{})
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &problem))
+		require.Equal(t, float64(recorder.Code), problem["status"])
+		return problem
+	}
+
+	handlerErr = &HTTPError{
+		Status:     http.StatusConflict,
+		Type:       "https://example.com/problems/conflict",
+		Detail:     "already exists",
+		Extensions: map[string]interface{}{"id": "foo"},
+		Err:        errors.New("duplicate key"),
+	}
+	problem := serve()
+	require.Equal(t, "https://example.com/problems/conflict", problem["type"])
+	require.Equal(t, "Conflict", problem["title"])
+	require.Equal(t, "already exists", problem["detail"])
+	require.Equal(t, "foo", problem["id"])
+	require.Equal(t, call.RequestId, problem["request_id"])
+	require.Equal(t, uint32(http.StatusConflict), call.StatusCode)
+	require.Equal(t, "409 Conflict: already exists: duplicate key", call.Error)
+
+	handlerErr = errors.New("database is down")
+	problem = serve()
+	require.Equal(t, "about:blank", problem["type"])
+	require.Equal(t, "Internal Server Error", problem["title"])
+	require.Nil(t, problem["detail"])
+	require.Equal(t, "database is down", call.Error)
+
+	handlerErr = &BindError{[]*FieldError{{Field: "ID", Source: "path", Key: "id", Err: errBindRequired}}}
+	problem = serve()
+	require.Equal(t, float64(http.StatusBadRequest), problem["status"])
+	require.Len(t, problem["errors"], 1)
+}
+
+func TestToHTTPErrorWrapped(t *testing.T) {
+	httpError := NewHTTPError(http.StatusNotFound, "no such user")
+	require.Equal(t, httpError, ToHTTPError(fmt.Errorf("load: %w", httpError)))
+	for _, testCase := range []struct {
+		err    error
+		status int
+	}{
+		{fmt.Errorf("read: %w", ErrRequestBodyTooLarge), http.StatusRequestEntityTooLarge},
+		{fmt.Errorf("query: %w", context.DeadlineExceeded), http.StatusGatewayTimeout},
+		{fmt.Errorf("open: %w", os.ErrNotExist), http.StatusNotFound},
+		{fmt.Errorf("open: %w", os.ErrPermission), http.StatusForbidden},
+		{fmt.Errorf("decode: %w", &DecodeError{StatusCode: http.StatusUnsupportedMediaType, Err: errors.New("foo")}), http.StatusUnsupportedMediaType},
+		{fmt.Errorf("bind: %w", &BindError{}), http.StatusBadRequest},
+		{errors.New("foo"), http.StatusInternalServerError},
+	} {
+		require.Equal(t, testCase.status, ToHTTPError(testCase.err).Status, testCase.err.Error())
+	}
+}
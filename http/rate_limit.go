@@ -0,0 +1,149 @@
+package pkghttp
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// how often buckets that have refilled to the burst are removed
+const rateLimitSweepInterval = time.Minute
+
+type rateLimiter struct {
+	rate      float64
+	burst     float64
+	keyFunc   RateLimitKeyFunc
+	now       func() time.Time
+	lock      *sync.Mutex
+	buckets   map[string]*rateLimitBucket
+	lastSweep time.Time
+}
+
+type rateLimitBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(opts RateLimitOptions) *rateLimiter {
+	burst := opts.Burst
+	if burst <= 0 {
+		burst = int(math.Ceil(opts.Rate))
+		if burst < 1 {
+			burst = 1
+		}
+	}
+	keyFunc := opts.KeyFunc
+	if keyFunc == nil {
+		keyFunc = RateLimitKeyIP
+	}
+	return &rateLimiter{
+		opts.Rate,
+		float64(burst),
+		keyFunc,
+		time.Now,
+		&sync.Mutex{},
+		make(map[string]*rateLimitBucket),
+		time.Time{},
+	}
+}
+
+func newRateLimitMiddleware(opts RateLimitOptions) Middleware {
+	return newRateLimiter(opts).wrap
+}
+
+func (r *rateLimiter) wrap(handler http.Handler) http.Handler {
+	return http.HandlerFunc(
+		func(responseWriter http.ResponseWriter, request *http.Request) {
+			key := r.keyFunc(request)
+			if key == "" {
+				handler.ServeHTTP(responseWriter, request)
+				return
+			}
+			if retryAfter, ok := r.allow(key); !ok {
+				if call := getCall(request.Context()); call != nil {
+					call.RateLimited = true
+				}
+				retryAfterSeconds := retryAfterSeconds(retryAfter)
+				responseWriter.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds))
+				writeHTTPError(
+					responseWriter,
+					request,
+					&HTTPError{
+						Status: http.StatusTooManyRequests,
+						Detail: fmt.Sprintf("rate limit exceeded, retry after %d seconds", retryAfterSeconds),
+					},
+				)
+				return
+			}
+			handler.ServeHTTP(responseWriter, request)
+		},
+	)
+}
+
+// allow takes a token for key, or returns the time until a token is available.
+func (r *rateLimiter) allow(key string) (time.Duration, bool) {
+	now := r.now()
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if now.Sub(r.lastSweep) > rateLimitSweepInterval {
+		r.sweep(now)
+	}
+	bucket, ok := r.buckets[key]
+	if !ok {
+		bucket = &rateLimitBucket{r.burst, now}
+		r.buckets[key] = bucket
+	}
+	bucket.tokens = r.tokens(bucket, now)
+	bucket.last = now
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		return 0, true
+	}
+	if r.rate <= 0 {
+		return rateLimitSweepInterval, false
+	}
+	return time.Duration((1 - bucket.tokens) / r.rate * float64(time.Second)), false
+}
+
+// must be called with lock held
+func (r *rateLimiter) sweep(now time.Time) {
+	for key, bucket := range r.buckets {
+		if r.tokens(bucket, now) >= r.burst {
+			delete(r.buckets, key)
+		}
+	}
+	r.lastSweep = now
+}
+
+func (r *rateLimiter) tokens(bucket *rateLimitBucket, now time.Time) float64 {
+	return math.Min(r.burst, bucket.tokens+now.Sub(bucket.last).Seconds()*r.rate)
+}
+
+func retryAfterSeconds(retryAfter time.Duration) int {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		return 1
+	}
+	return seconds
+}
+
+func rateLimitKeyIP(request *http.Request) string {
+	host, _, err := net.SplitHostPort(request.RemoteAddr)
+	if err != nil {
+		return request.RemoteAddr
+	}
+	return host
+}
+
+func newRateLimitKeyHeader(header string) RateLimitKeyFunc {
+	return func(request *http.Request) string {
+		if value := request.Header.Get(header); value != "" {
+			return header + ":" + value
+		}
+		return rateLimitKeyIP(request)
+	}
+}
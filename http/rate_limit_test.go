@@ -0,0 +1,67 @@
+package pkghttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimit(t *testing.T) {
+	now := time.Unix(0, 0)
+	rateLimiter := newRateLimiter(
+		RateLimitOptions{
+			Rate:    0.5,
+			Burst:   2,
+			KeyFunc: NewRateLimitKeyHeader("X-API-Key"),
+		},
+	)
+	rateLimiter.now = func() time.Time { return now }
+	var call *Call
+	handler := NewChain(
+		NewCallLogMiddleware(CallLogOptions{}),
+		func(handler http.Handler) http.Handler {
+			return http.HandlerFunc(
+				func(responseWriter http.ResponseWriter, request *http.Request) {
+					call = CallFromContext(request.Context())
+					handler.ServeHTTP(responseWriter, request)
+				},
+			)
+		},
+		rateLimiter.wrap,
+	).Then(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	serve := func(apiKey string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest("GET", "/foo", nil)
+		if apiKey != "" {
+			request.Header.Set("X-API-Key", apiKey)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	require.Equal(t, http.StatusOK, serve("a").Code)
+	require.Equal(t, http.StatusOK, serve("a").Code)
+	recorder := serve("a")
+	require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	require.Equal(t, "2", recorder.Header().Get("Retry-After"))
+	require.Equal(t, ContentTypeProblemJSON, recorder.Header().Get("Content-Type"))
+	require.Contains(t, recorder.Body.String(), `"detail":"rate limit exceeded, retry after 2 seconds"`)
+	require.True(t, call.RateLimited)
+	require.Equal(t, uint32(http.StatusTooManyRequests), call.StatusCode)
+	require.Equal(t, "", call.Error)
+
+	require.Equal(t, http.StatusOK, serve("b").Code)
+	require.False(t, call.RateLimited)
+	require.Equal(t, http.StatusOK, serve("").Code)
+
+	now = now.Add(2 * time.Second)
+	require.Equal(t, http.StatusOK, serve("a").Code)
+	require.Equal(t, http.StatusTooManyRequests, serve("a").Code)
+
+	now = now.Add(2 * time.Minute)
+	require.Equal(t, http.StatusOK, serve("c").Code)
+	require.Len(t, rateLimiter.buckets, 1)
+}
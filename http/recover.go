@@ -0,0 +1,147 @@
+package pkghttp
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"runtime"
+
+	"go.pedge.io/lion/proto"
+)
+
+var recoverHTMLTemplate = template.Must(
+	template.New("recover").Parse(`<!DOCTYPE html>
+<html>
+<head><title>500 Internal Server Error</title></head>
+<body>
+<h1>Internal Server Error</h1>
+<p>Incident ID: {{.IncidentID}}</p>
+{{if .RequestID}}<p>Request ID: {{.RequestID}}</p>
+{{end}}</body>
+</html>
+`),
+)
+
+type recoverBody struct {
+	Error      string `json:"error"`
+	IncidentID string `json:"incident_id"`
+	RequestID  string `json:"request_id,omitempty"`
+}
+
+func newRecoverMiddleware(opts RecoverOptions) Middleware {
+	recoverHandler := opts.Handler
+	if recoverHandler == nil {
+		switch opts.Format {
+		case RecoverFormatHTML:
+			recoverHandler = writeRecoverHTML
+		default:
+			recoverHandler = writeRecoverJSON
+		}
+	}
+	return func(handler http.Handler) http.Handler {
+		return http.HandlerFunc(
+			func(responseWriter http.ResponseWriter, request *http.Request) {
+				wrapperResponseWriter, responseWriter := newWrapperResponseWriter(responseWriter)
+				defer func() {
+					recoverErr := recover()
+					if recoverErr == nil {
+						return
+					}
+					if recoverErr == http.ErrAbortHandler {
+						// the handler intentionally aborted the response
+						panic(recoverErr)
+					}
+					p := &Panic{
+						IncidentID: newRequestID(),
+						Value:      recoverErr,
+						Stack:      fullStack(),
+					}
+					written := wrapperResponseWriter.Written()
+					if !written {
+						header := wrapperResponseWriter.Header()
+						// these may have been set for the body the handler intended to write
+						header.Del("Content-Encoding")
+						header.Del("Content-Length")
+						recoverHandler(wrapperResponseWriter, request, p)
+					}
+					panicString := fmt.Sprintf("panic: %v\n%s", p.Value, string(p.Stack))
+					if call := getCall(request.Context()); call != nil {
+						call.Error = panicString
+						call.IncidentId = p.IncidentID
+					} else {
+						// not logged by a call log middleware
+						call := &Call{
+							Method:     request.Method,
+							StatusCode: uint32(statusCode(wrapperResponseWriter.StatusCode())),
+							Error:      panicString,
+							RequestId:  RequestIDFromContext(request.Context()),
+							IncidentId: p.IncidentID,
+						}
+						if request.URL != nil {
+							call.Path = request.URL.Path
+						}
+						protolion.Error(call)
+					}
+					if written {
+						// the status code has already been sent, so abort the connection
+						// so that the client does not take a partial response as complete
+						panic(http.ErrAbortHandler)
+					}
+				}()
+				handler.ServeHTTP(responseWriter, request)
+			},
+		)
+	}
+}
+
+func writeRecoverJSON(responseWriter http.ResponseWriter, request *http.Request, p *Panic) {
+	setRecoverHeaders(responseWriter, "application/json; charset=utf-8")
+	responseWriter.WriteHeader(http.StatusInternalServerError)
+	_ = json.NewEncoder(responseWriter).Encode(
+		&recoverBody{
+			Error:      http.StatusText(http.StatusInternalServerError),
+			IncidentID: p.IncidentID,
+			RequestID:  RequestIDFromContext(request.Context()),
+		},
+	)
+}
+
+func writeRecoverHTML(responseWriter http.ResponseWriter, request *http.Request, p *Panic) {
+	setRecoverHeaders(responseWriter, "text/html; charset=utf-8")
+	responseWriter.WriteHeader(http.StatusInternalServerError)
+	_ = recoverHTMLTemplate.Execute(
+		responseWriter,
+		&recoverBody{
+			IncidentID: p.IncidentID,
+			RequestID:  RequestIDFromContext(request.Context()),
+		},
+	)
+}
+
+func setRecoverHeaders(responseWriter http.ResponseWriter, contentType string) {
+	header := responseWriter.Header()
+	header.Set("Content-Type", contentType)
+	header.Set("Cache-Control", "no-store")
+	header.Set("X-Content-Type-Options", "nosniff")
+}
+
+func newRecoverFormat(s string) (RecoverFormat, error) {
+	switch recoverFormat := RecoverFormat(s); recoverFormat {
+	case "":
+		return RecoverFormatJSON, nil
+	case RecoverFormatJSON, RecoverFormatHTML:
+		return recoverFormat, nil
+	default:
+		return "", fmt.Errorf("pkghttp: invalid recover format %s, must be one of json, html", s)
+	}
+}
+
+func fullStack() []byte {
+	for size := 8192; ; size *= 2 {
+		stack := make([]byte, size)
+		if n := runtime.Stack(stack, false); n < size {
+			return stack[:n]
+		}
+	}
+}
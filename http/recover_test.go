@@ -0,0 +1,132 @@
+package pkghttp
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecoverJSON(t *testing.T) {
+	var call *Call
+	handler := NewChain(
+		NewCallLogMiddleware(CallLogOptions{}),
+		func(handler http.Handler) http.Handler {
+			return http.HandlerFunc(
+				func(responseWriter http.ResponseWriter, request *http.Request) {
+					call = CallFromContext(request.Context())
+					handler.ServeHTTP(responseWriter, request)
+				},
+			)
+		},
+		NewRequestIDMiddleware(),
+		NewRecoverMiddleware(RecoverOptions{}),
+	).Then(
+		http.HandlerFunc(
+			func(responseWriter http.ResponseWriter, request *http.Request) {
+				responseWriter.Header().Set("Content-Length", "100")
+				panic("oops")
+			},
+		),
+	)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/foo", nil))
+	require.Equal(t, http.StatusInternalServerError, recorder.Code)
+	require.Equal(t, "application/json; charset=utf-8", recorder.Header().Get("Content-Type"))
+	require.Equal(t, "", recorder.Header().Get("Content-Length"))
+	body := &recoverBody{}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), body))
+	require.Equal(t, "Internal Server Error", body.Error)
+	require.Len(t, body.IncidentID, 32)
+	require.Equal(t, call.RequestId, body.RequestID)
+	require.Equal(t, body.IncidentID, call.IncidentId)
+	require.Equal(t, uint32(http.StatusInternalServerError), call.StatusCode)
+	require.Contains(t, call.Error, "panic: oops")
+	require.Contains(t, call.Error, "TestRecoverJSON")
+}
+
+func TestRecoverHTML(t *testing.T) {
+	handler := NewRecoverMiddleware(RecoverOptions{Format: RecoverFormatHTML})(
+		http.HandlerFunc(func(http.ResponseWriter, *http.Request) { panic("oops") }),
+	)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/foo", nil))
+	require.Equal(t, http.StatusInternalServerError, recorder.Code)
+	require.Equal(t, "text/html; charset=utf-8", recorder.Header().Get("Content-Type"))
+	require.Contains(t, recorder.Body.String(), "Incident ID: ")
+}
+
+func TestRecoverResponseStarted(t *testing.T) {
+	var call *Call
+	handler := NewChain(
+		NewCallLogMiddleware(CallLogOptions{}),
+		func(handler http.Handler) http.Handler {
+			return http.HandlerFunc(
+				func(responseWriter http.ResponseWriter, request *http.Request) {
+					call = CallFromContext(request.Context())
+					handler.ServeHTTP(responseWriter, request)
+				},
+			)
+		},
+		NewRecoverMiddleware(RecoverOptions{}),
+	).Then(
+		http.HandlerFunc(
+			func(responseWriter http.ResponseWriter, request *http.Request) {
+				_, _ = responseWriter.Write([]byte("partial"))
+				panic("oops")
+			},
+		),
+	)
+	recorder := httptest.NewRecorder()
+	var recoverErr interface{}
+	func() {
+		defer func() {
+			recoverErr = recover()
+		}()
+		handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/foo", nil))
+	}()
+	require.Equal(t, http.ErrAbortHandler, recoverErr)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, "partial", recorder.Body.String())
+	require.Contains(t, call.Error, "panic: oops")
+	require.Len(t, call.IncidentId, 32)
+}
+
+type testHijackResponseWriter struct {
+	*httptest.ResponseRecorder
+}
+
+func (w *testHijackResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return nil, nil, nil
+}
+
+func TestRecoverFlushedOrHijacked(t *testing.T) {
+	for _, start := range []func(http.ResponseWriter){
+		func(responseWriter http.ResponseWriter) { responseWriter.(http.Flusher).Flush() },
+		func(responseWriter http.ResponseWriter) { _, _, _ = responseWriter.(http.Hijacker).Hijack() },
+	} {
+		handler := NewRecoverMiddleware(RecoverOptions{})(
+			http.HandlerFunc(
+				func(responseWriter http.ResponseWriter, request *http.Request) {
+					start(responseWriter)
+					panic("oops")
+				},
+			),
+		)
+		recorder := httptest.NewRecorder()
+		var recoverErr interface{}
+		func() {
+			defer func() {
+				recoverErr = recover()
+			}()
+			handler.ServeHTTP(&testHijackResponseWriter{recorder}, httptest.NewRequest("GET", "/foo", nil))
+		}()
+		require.Equal(t, http.ErrAbortHandler, recoverErr)
+		require.Equal(t, "", recorder.Body.String())
+		require.Equal(t, "", recorder.Header().Get("Content-Type"))
+	}
+}
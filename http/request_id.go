@@ -0,0 +1,53 @@
+package pkghttp
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+const maxRequestIDLength = 128
+
+type requestIDKey struct{}
+
+func newRequestIDMiddleware() Middleware {
+	return func(handler http.Handler) http.Handler {
+		return http.HandlerFunc(
+			func(responseWriter http.ResponseWriter, request *http.Request) {
+				requestID := request.Header.Get(RequestIDHeader)
+				if !isValidRequestID(requestID) {
+					requestID = newRequestID()
+				}
+				responseWriter.Header().Set(RequestIDHeader, requestID)
+				if call := getCall(request.Context()); call != nil {
+					call.RequestId = requestID
+				}
+				handler.ServeHTTP(
+					responseWriter,
+					request.WithContext(context.WithValue(request.Context(), requestIDKey{}, requestID)),
+				)
+			},
+		)
+	}
+}
+
+func newRequestID() string {
+	data := make([]byte, 16)
+	// crypto/rand.Read only fails if the system randomness source does, in which case
+	// a zero request ID is better than failing the request
+	_, _ = rand.Read(data)
+	return hex.EncodeToString(data)
+}
+
+func isValidRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(requestID); i++ {
+		if requestID[i] < 0x21 || requestID[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
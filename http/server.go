@@ -0,0 +1,360 @@
+package pkghttp
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"go.pedge.io/lion/proto"
+	"go.pedge.io/pb/go/google/protobuf"
+)
+
+type namedShutdownHook struct {
+	name string
+	hook ShutdownHook
+}
+
+type server struct {
+	handlerEnv      HandlerEnv
+	listenAddresses []listenAddress
+	unixSocketMode  os.FileMode
+	httpServer      *http.Server
+	debugServer     *http.Server
+	tlsConfig       *tls.Config
+	shutdownTimeout time.Duration
+	preStopDelay    time.Duration
+	lock            *sync.Mutex
+	shutdownHooks   []namedShutdownHook
+	started         bool
+	// logs the Calls for requests rejected by net/http before they reach the handler
+	logCall func(*Call)
+}
+
+func newServer(handler http.Handler, handlerEnv HandlerEnv, opts ServerOptions) (*server, error) {
+	if handler == nil {
+		return nil, ErrRequireHandler
+	}
+	handlerEnv = setHandlerEnvDefaults(handlerEnv)
+	// the default chain validates handlerEnv even if it is not used
+	defaultChain, err := NewDefaultChain(handlerEnv)
+	if err != nil {
+		return nil, err
+	}
+	chain := opts.Chain
+	if chain == nil {
+		chain = defaultChain
+	}
+	tlsConfig, err := NewTLSConfig(handlerEnv)
+	if err != nil {
+		return nil, err
+	}
+	listenAddresses, err := parseListenAddresses(handlerEnv)
+	if err != nil {
+		return nil, err
+	}
+	unixSocketMode, err := parseUnixSocketMode(handlerEnv.UnixSocketMode)
+	if err != nil {
+		return nil, err
+	}
+	var debugServer *http.Server
+	if handlerEnv.DebugPort != 0 {
+		for _, listenAddress := range listenAddresses {
+			if listenAddress.network != listenNetworkTCP {
+				continue
+			}
+			if _, port, _ := net.SplitHostPort(listenAddress.address); port == strconv.Itoa(int(handlerEnv.DebugPort)) {
+				return nil, fmt.Errorf("pkghttp: debug port %d must be different from the port of %s", handlerEnv.DebugPort, listenAddress.address)
+			}
+		}
+		// no read or write timeout, as profiles can take longer than the timeouts
+		debugServer = &http.Server{
+			Handler:           NewDebugHandler(opts.Debug),
+			ReadHeaderTimeout: time.Duration(handlerEnv.ReadHeaderTimeoutSec) * time.Second,
+			IdleTimeout:       time.Duration(handlerEnv.IdleTimeoutSec) * time.Second,
+		}
+	}
+	writeTimeout := time.Duration(handlerEnv.WriteTimeoutSec) * time.Second
+	s := &server{
+		handlerEnv,
+		listenAddresses,
+		unixSocketMode,
+		&http.Server{
+			Handler:           newLimitConnHandler(chain.Then(handler), writeTimeout),
+			ReadTimeout:       time.Duration(handlerEnv.ReadTimeoutSec) * time.Second,
+			ReadHeaderTimeout: time.Duration(handlerEnv.ReadHeaderTimeoutSec) * time.Second,
+			WriteTimeout:      writeTimeout,
+			IdleTimeout:       time.Duration(handlerEnv.IdleTimeoutSec) * time.Second,
+			MaxHeaderBytes:    int(handlerEnv.MaxHeaderBytes),
+			ConnContext:       limitConnContext,
+			// set here rather than wrapping the listeners so that net/http sets up HTTP/2
+			TLSConfig: tlsConfig,
+		},
+		debugServer,
+		tlsConfig,
+		time.Duration(handlerEnv.ShutdownTimeoutSec) * time.Second,
+		time.Duration(handlerEnv.PreStopDelaySec) * time.Second,
+		&sync.Mutex{},
+		nil,
+		false,
+		func(call *Call) { protolion.Info(call) },
+	}
+	s.httpServer.ConnState = s.connState
+	return s, nil
+}
+
+// connState logs a Call for a request that net/http rejected for exceeding
+// MaxHeaderBytes or ReadHeaderTimeout, which never reaches the handler.
+// HTTP/2 conns are not checked, as they are not read request by request.
+func (s *server) connState(conn net.Conn, connState http.ConnState) {
+	limitConn := toLimitConn(conn)
+	if limitConn == nil {
+		return
+	}
+	switch connState {
+	case http.StateIdle:
+		limitConn.reset()
+	case http.StateClosed:
+		if tlsConn, ok := conn.(*tls.Conn); ok && tlsConn.ConnectionState().NegotiatedProtocol == "h2" {
+			return
+		}
+		if limitExceeded, statusCode := limitConn.headerLimitExceeded(int64(s.handlerEnv.MaxHeaderBytes)); limitExceeded != "" {
+			s.logCall(
+				&Call{
+					StatusCode:    uint32(statusCode),
+					LimitExceeded: limitExceeded,
+					Error:         fmt.Sprintf("request from %s rejected before it was read", conn.RemoteAddr().String()),
+				},
+			)
+		}
+	}
+}
+
+func (s *server) OnShutdown(name string, shutdownHook ShutdownHook) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.shutdownHooks = append(s.shutdownHooks, namedShutdownHook{name, shutdownHook})
+}
+
+func (s *server) Serve(ctx context.Context) error {
+	s.lock.Lock()
+	if s.started {
+		s.lock.Unlock()
+		return ErrServerAlreadyStarted
+	}
+	s.started = true
+	s.lock.Unlock()
+
+	listeners, err := listen(s.listenAddresses, s.unixSocketMode)
+	if err != nil {
+		handleErrorBeforeStart(err)
+		_ = s.runShutdownHooks()
+		return err
+	}
+	var debugListener net.Listener
+	if s.debugServer != nil {
+		debugListener, err = net.Listen("tcp", net.JoinHostPort(s.handlerEnv.DebugHost, strconv.Itoa(int(s.handlerEnv.DebugPort))))
+		if err != nil {
+			for _, listener := range listeners {
+				_ = listener.Close()
+			}
+			handleErrorBeforeStart(err)
+			_ = s.runShutdownHooks()
+			return err
+		}
+	}
+	addresses := make([]string, len(listeners))
+	for i, listener := range listeners {
+		addresses[i] = listenerAddress(listener)
+	}
+	protolion.Info(
+		&ServerStarting{
+			Port:      uint32(s.handlerEnv.Port),
+			Tls:       s.tlsConfig != nil,
+			DebugPort: uint32(s.handlerEnv.DebugPort),
+			Addresses: addresses,
+		},
+	)
+	start := time.Now()
+	errC := make(chan error, len(listeners)+1)
+	waitGroup := &sync.WaitGroup{}
+	for i, listener := range listeners {
+		listener = &limitListener{listener}
+		serve(waitGroup, errC, s.httpServer, s.tlsConfig != nil, listener, fmt.Sprintf("server on %s", addresses[i]))
+	}
+	if s.debugServer != nil {
+		serve(waitGroup, errC, s.debugServer, false, debugListener, "debug server")
+	}
+	signalC := make(chan os.Signal, 1)
+	signal.Notify(signalC, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signalC)
+
+	var reason string
+	select {
+	case err = <-errC:
+		reason = "server failed"
+	case <-ctx.Done():
+		reason = fmt.Sprintf("context done: %s", ctx.Err().Error())
+	case sig := <-signalC:
+		reason = fmt.Sprintf("received signal %s", sig.String())
+	}
+	if shutdownErr := s.shutdown(err == nil, waitGroup, signalC); err == nil {
+		err = shutdownErr
+	}
+	if hookErr := s.runShutdownHooks(); err == nil {
+		err = hookErr
+	}
+	// DefaultHealthRegistry outlives the server, such as for a server started after this one
+	DefaultHealthRegistry.ClearShuttingDown()
+	serverFinished := &ServerFinished{
+		Duration: google_protobuf.DurationToProto(time.Since(start)),
+		Reason:   reason,
+	}
+	if err != nil {
+		serverFinished.Error = err.Error()
+		protolion.Error(serverFinished)
+		return err
+	}
+	protolion.Info(serverFinished)
+	return nil
+}
+
+// shutdown fails readiness, waits for the pre-stop delay if drain is set, and then
+// waits for the requests in flight to finish. A signal on signalC ends the pre-stop
+// delay. The debug server is shut down last so that it can be used while the requests
+// in flight finish.
+func (s *server) shutdown(drain bool, waitGroup *sync.WaitGroup, signalC <-chan os.Signal) error {
+	DefaultHealthRegistry.SetShuttingDown()
+	if drain && s.preStopDelay > 0 {
+		timer := time.NewTimer(s.preStopDelay)
+		select {
+		case <-timer.C:
+		case <-signalC:
+		}
+		timer.Stop()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
+	defer cancel()
+	err := shutdownHTTPServer(ctx, s.httpServer)
+	if s.debugServer != nil {
+		if debugErr := shutdownHTTPServer(ctx, s.debugServer); err == nil {
+			err = debugErr
+		}
+	}
+	waitGroup.Wait()
+	return err
+}
+
+// runShutdownHooks runs the shutdown hooks in order, and returns the first error.
+func (s *server) runShutdownHooks() error {
+	s.lock.Lock()
+	shutdownHooks := s.shutdownHooks
+	s.lock.Unlock()
+	if len(shutdownHooks) == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
+	defer cancel()
+	var firstErr error
+	for _, shutdownHook := range shutdownHooks {
+		start := time.Now()
+		err := shutdownHook.hook(ctx)
+		shutdownHookFinished := &ShutdownHookFinished{
+			Name:     shutdownHook.name,
+			Duration: google_protobuf.DurationToProto(time.Since(start)),
+		}
+		if err != nil {
+			shutdownHookFinished.Error = err.Error()
+			protolion.Error(shutdownHookFinished)
+			if firstErr == nil {
+				firstErr = fmt.Errorf("pkghttp: shutdown hook %s: %s", shutdownHook.name, err.Error())
+			}
+			continue
+		}
+		protolion.Info(shutdownHookFinished)
+	}
+	return firstErr
+}
+
+// serve serves httpServer on listener, with the TLSConfig of httpServer if useTLS is set.
+// httpServer.TLSConfig is not checked here, as net/http can set it once serving starts.
+func serve(waitGroup *sync.WaitGroup, errC chan<- error, httpServer *http.Server, useTLS bool, listener net.Listener, name string) {
+	waitGroup.Add(1)
+	go func() {
+		defer waitGroup.Done()
+		var err error
+		if useTLS {
+			// the certificate is set by the TLSConfig
+			err = httpServer.ServeTLS(listener, "", "")
+		} else {
+			err = httpServer.Serve(listener)
+		}
+		if err != http.ErrServerClosed {
+			errC <- fmt.Errorf("pkghttp: %s: %s", name, err.Error())
+		}
+	}()
+}
+
+func listenerAddress(listener net.Listener) string {
+	addr := listener.Addr()
+	if addr.Network() == "unix" {
+		return fmt.Sprintf("unix:%s", addr.String())
+	}
+	return addr.String()
+}
+
+func shutdownHTTPServer(ctx context.Context, httpServer *http.Server) error {
+	if err := httpServer.Shutdown(ctx); err != nil {
+		// the requests in flight did not finish in time
+		_ = httpServer.Close()
+		return err
+	}
+	return nil
+}
+
+func setHandlerEnvDefaults(handlerEnv HandlerEnv) HandlerEnv {
+	if handlerEnv.Port == 0 {
+		handlerEnv.Port = 8080
+	}
+	if handlerEnv.HealthCheckPath == "" {
+		handlerEnv.HealthCheckPath = "/health"
+	}
+	if handlerEnv.LivenessPath == "" {
+		handlerEnv.LivenessPath = "/health/live"
+	}
+	if handlerEnv.ReadinessPath == "" {
+		handlerEnv.ReadinessPath = "/health/ready"
+	}
+	if handlerEnv.DebugHost == "" {
+		handlerEnv.DebugHost = "127.0.0.1"
+	}
+	if handlerEnv.ReadTimeoutSec == 0 {
+		handlerEnv.ReadTimeoutSec = 30
+	}
+	if handlerEnv.ReadHeaderTimeoutSec == 0 {
+		handlerEnv.ReadHeaderTimeoutSec = 10
+	}
+	if handlerEnv.WriteTimeoutSec == 0 {
+		handlerEnv.WriteTimeoutSec = 60
+	}
+	if handlerEnv.IdleTimeoutSec == 0 {
+		handlerEnv.IdleTimeoutSec = 120
+	}
+	if handlerEnv.MaxHeaderBytes == 0 {
+		handlerEnv.MaxHeaderBytes = 1 << 20
+	}
+	if handlerEnv.MaxBodyBytes == 0 {
+		handlerEnv.MaxBodyBytes = 10 << 20
+	}
+	if handlerEnv.ShutdownTimeoutSec == 0 {
+		handlerEnv.ShutdownTimeoutSec = 10
+	}
+	return handlerEnv
+}
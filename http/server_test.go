@@ -0,0 +1,133 @@
+package pkghttp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerShutdown(t *testing.T) {
+	port := testFreePort(t)
+	server, err := NewServer(
+		http.HandlerFunc(
+			func(responseWriter http.ResponseWriter, request *http.Request) {
+				_, _ = responseWriter.Write([]byte("hello"))
+			},
+		),
+		HandlerEnv{Port: port},
+		ServerOptions{},
+	)
+	require.NoError(t, err)
+	var hooks []string
+	server.OnShutdown(
+		"first",
+		func(ctx context.Context) error {
+			hooks = append(hooks, "first")
+			return nil
+		},
+	)
+	server.OnShutdown(
+		"second",
+		func(ctx context.Context) error {
+			hooks = append(hooks, "second")
+			return errors.New("oops")
+		},
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	errC := make(chan error, 1)
+	go func() {
+		errC <- server.Serve(ctx)
+	}()
+	var response *http.Response
+	for i := 0; i < 100; i++ {
+		if response, err = http.Get(fmt.Sprintf("http://localhost:%d/foo", port)); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.NoError(t, err)
+	data, err := ioutil.ReadAll(response.Body)
+	require.NoError(t, err)
+	require.NoError(t, response.Body.Close())
+	require.Equal(t, "hello", string(data))
+
+	cancel()
+	err = <-errC
+	require.Error(t, err)
+	require.Equal(t, "pkghttp: shutdown hook second: oops", err.Error())
+	require.Equal(t, []string{"first", "second"}, hooks)
+	require.Equal(t, ErrServerAlreadyStarted, server.Serve(context.Background()))
+	// readiness is restored for the servers after this one
+	require.False(t, DefaultHealthRegistry.Readiness(context.Background()).ShuttingDown)
+}
+
+func TestServerPreStopDelaySignal(t *testing.T) {
+	server, err := newServer(http.NotFoundHandler(), HandlerEnv{PreStopDelaySec: 60}, ServerOptions{})
+	require.NoError(t, err)
+	defer DefaultHealthRegistry.ClearShuttingDown()
+	signalC := make(chan os.Signal, 1)
+	signalC <- syscall.SIGTERM
+	start := time.Now()
+	require.NoError(t, server.shutdown(true, &sync.WaitGroup{}, signalC))
+	require.True(t, time.Since(start) < 10*time.Second)
+	require.True(t, DefaultHealthRegistry.Readiness(context.Background()).ShuttingDown)
+}
+
+func testFreePort(t *testing.T) uint16 {
+	listener, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	port := listener.Addr().(*net.TCPAddr).Port
+	require.NoError(t, listener.Close())
+	return uint16(port)
+}
+
+func TestServerDebugHost(t *testing.T) {
+	port := testFreePort(t)
+	debugPort := testFreePort(t)
+	server, err := NewServer(http.NotFoundHandler(), HandlerEnv{Port: port, DebugPort: debugPort}, ServerOptions{})
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	errC := make(chan error, 1)
+	go func() {
+		errC <- server.Serve(ctx)
+	}()
+	var response *http.Response
+	for i := 0; i < 100; i++ {
+		if response, err = http.Get(fmt.Sprintf("http://127.0.0.1:%d/debug/buildinfo", debugPort)); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.NoError(t, err)
+	require.NoError(t, response.Body.Close())
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	if ip := testNonLoopbackIP(); ip != nil {
+		_, err = net.DialTimeout("tcp", net.JoinHostPort(ip.String(), fmt.Sprint(debugPort)), time.Second)
+		require.Error(t, err)
+	}
+	cancel()
+	require.NoError(t, <-errC)
+}
+
+func testNonLoopbackIP() net.IP {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && !ipNet.IP.IsLoopback() && ipNet.IP.To4() != nil {
+			return ipNet.IP
+		}
+	}
+	return nil
+}
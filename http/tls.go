@@ -0,0 +1,125 @@
+package pkghttp
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+// the minimum time between checks of the certificate files for changes
+const certReloadInterval = time.Second
+
+var (
+	errTLSRequireCertAndKey   = errors.New("pkghttp: both the TLS cert file and key file must be set")
+	errTLSClientCARequireCert = errors.New("pkghttp: the TLS cert file and key file must be set to use a client CA")
+
+	tlsVersions = map[string]uint16{
+		"1.0": tls.VersionTLS10,
+		"1.1": tls.VersionTLS11,
+		"1.2": tls.VersionTLS12,
+		"1.3": tls.VersionTLS13,
+	}
+)
+
+func newTLSConfig(handlerEnv HandlerEnv) (*tls.Config, error) {
+	if handlerEnv.TLSCertFile == "" && handlerEnv.TLSKeyFile == "" {
+		if handlerEnv.TLSClientCAFile != "" {
+			return nil, errTLSClientCARequireCert
+		}
+		return nil, nil
+	}
+	if handlerEnv.TLSCertFile == "" || handlerEnv.TLSKeyFile == "" {
+		return nil, errTLSRequireCertAndKey
+	}
+	minVersion := uint16(tls.VersionTLS12)
+	if handlerEnv.TLSMinVersion != "" {
+		var ok bool
+		if minVersion, ok = tlsVersions[handlerEnv.TLSMinVersion]; !ok {
+			return nil, fmt.Errorf("pkghttp: unknown TLS version %s", handlerEnv.TLSMinVersion)
+		}
+	}
+	certReloader, err := newCertReloader(handlerEnv.TLSCertFile, handlerEnv.TLSKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig := &tls.Config{
+		MinVersion:     minVersion,
+		GetCertificate: certReloader.GetCertificate,
+	}
+	if handlerEnv.TLSClientCAFile != "" {
+		data, err := ioutil.ReadFile(handlerEnv.TLSClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		clientCAs := x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("pkghttp: no certificates in TLS client CA file %s", handlerEnv.TLSClientCAFile)
+		}
+		tlsConfig.ClientCAs = clientCAs
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return tlsConfig, nil
+}
+
+// certReloader reloads a certificate when its files change.
+type certReloader struct {
+	certFile    string
+	keyFile     string
+	lock        *sync.Mutex
+	cert        *tls.Certificate
+	certModTime time.Time
+	keyModTime  time.Time
+	lastCheck   time.Time
+}
+
+func newCertReloader(certFile string, keyFile string) (*certReloader, error) {
+	c := &certReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+		lock:     &sync.Mutex{},
+	}
+	if err := c.reloadIfChanged(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if time.Since(c.lastCheck) >= certReloadInterval {
+		// if the files are mid-update, keep serving the current certificate
+		// and try again on a later handshake
+		_ = c.reloadIfChanged()
+	}
+	return c.cert, nil
+}
+
+// must be called with lock held, except on construction
+func (c *certReloader) reloadIfChanged() error {
+	c.lastCheck = time.Now()
+	certFileInfo, err := os.Stat(c.certFile)
+	if err != nil {
+		return err
+	}
+	keyFileInfo, err := os.Stat(c.keyFile)
+	if err != nil {
+		return err
+	}
+	if c.cert != nil && certFileInfo.ModTime().Equal(c.certModTime) && keyFileInfo.ModTime().Equal(c.keyModTime) {
+		return nil
+	}
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return err
+	}
+	c.cert = &cert
+	c.certModTime = certFileInfo.ModTime()
+	c.keyModTime = keyFileInfo.ModTime()
+	return nil
+}
@@ -0,0 +1,218 @@
+package pkghttp
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTLSConfig(t *testing.T) {
+	dirPath := testTempDir(t)
+	ca := newTestCert(t, "ca", nil)
+	certFile, keyFile := ca.write(t, dirPath, "server")
+	caFile, _ := ca.write(t, dirPath, "ca")
+
+	tlsConfig, err := NewTLSConfig(HandlerEnv{})
+	require.NoError(t, err)
+	require.Nil(t, tlsConfig)
+	tlsConfig, err = NewTLSConfig(HandlerEnv{TLSCertFile: certFile, TLSKeyFile: keyFile})
+	require.NoError(t, err)
+	require.Equal(t, uint16(tls.VersionTLS12), tlsConfig.MinVersion)
+	require.Nil(t, tlsConfig.ClientCAs)
+	tlsConfig, err = NewTLSConfig(HandlerEnv{TLSCertFile: certFile, TLSKeyFile: keyFile, TLSMinVersion: "1.3"})
+	require.NoError(t, err)
+	require.Equal(t, uint16(tls.VersionTLS13), tlsConfig.MinVersion)
+	tlsConfig, err = NewTLSConfig(HandlerEnv{TLSCertFile: certFile, TLSKeyFile: keyFile, TLSClientCAFile: caFile})
+	require.NoError(t, err)
+	require.NotNil(t, tlsConfig.ClientCAs)
+	require.Equal(t, tls.RequireAndVerifyClientCert, tlsConfig.ClientAuth)
+
+	for _, handlerEnv := range []HandlerEnv{
+		{TLSCertFile: certFile, TLSKeyFile: keyFile, TLSMinVersion: "1.4"},
+		{TLSCertFile: certFile},
+		{TLSKeyFile: keyFile},
+		{TLSClientCAFile: caFile},
+		{TLSCertFile: certFile, TLSKeyFile: keyFile, TLSClientCAFile: filepath.Join(dirPath, "missing.pem")},
+		{TLSCertFile: certFile, TLSKeyFile: filepath.Join(dirPath, "missing.pem")},
+	} {
+		_, err := NewTLSConfig(handlerEnv)
+		require.Error(t, err)
+	}
+}
+
+func TestCertReloader(t *testing.T) {
+	dirPath := testTempDir(t)
+	certFile, keyFile := newTestCert(t, "first", nil).write(t, dirPath, "server")
+	certReloader, err := newCertReloader(certFile, keyFile)
+	require.NoError(t, err)
+	require.Equal(t, "first", testLeafCommonName(t, certReloader))
+
+	certFile, keyFile = newTestCert(t, "second", nil).write(t, dirPath, "server")
+	modTime := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(certFile, modTime, modTime))
+	require.NoError(t, os.Chtimes(keyFile, modTime, modTime))
+	// not checked again within certReloadInterval
+	require.Equal(t, "first", testLeafCommonName(t, certReloader))
+	certReloader.lastCheck = time.Now().Add(-certReloadInterval)
+	require.Equal(t, "second", testLeafCommonName(t, certReloader))
+
+	// the current certificate is kept while the files are invalid
+	require.NoError(t, ioutil.WriteFile(keyFile, []byte("foo"), 0600))
+	modTime = modTime.Add(time.Minute)
+	require.NoError(t, os.Chtimes(keyFile, modTime, modTime))
+	certReloader.lastCheck = time.Now().Add(-certReloadInterval)
+	require.Equal(t, "second", testLeafCommonName(t, certReloader))
+}
+
+func TestServerTLS(t *testing.T) {
+	dirPath := testTempDir(t)
+	ca := newTestCert(t, "ca", nil)
+	caFile, _ := ca.write(t, dirPath, "ca")
+	certFile, keyFile := newTestCert(t, "localhost", ca).write(t, dirPath, "server")
+	clientCert := newTestCert(t, "client", ca)
+	port := testFreePort(t)
+	server, err := NewServer(
+		http.HandlerFunc(
+			func(responseWriter http.ResponseWriter, request *http.Request) {
+				_, _ = fmt.Fprintf(responseWriter, "%d %s", request.ProtoMajor, request.TLS.PeerCertificates[0].Subject.CommonName)
+			},
+		),
+		HandlerEnv{
+			Port:            port,
+			TLSCertFile:     certFile,
+			TLSKeyFile:      keyFile,
+			TLSClientCAFile: caFile,
+		},
+		ServerOptions{},
+	)
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	errC := make(chan error, 1)
+	go func() {
+		errC <- server.Serve(ctx)
+	}()
+	rootCAs := x509.NewCertPool()
+	rootCAs.AddCert(ca.cert)
+	newClient := func(certificates ...tls.Certificate) *http.Client {
+		return &http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{
+					RootCAs:      rootCAs,
+					Certificates: certificates,
+				},
+				ForceAttemptHTTP2: true,
+			},
+		}
+	}
+	url := fmt.Sprintf("https://localhost:%d/foo", port)
+
+	client := newClient(clientCert.tlsCertificate())
+	var response *http.Response
+	for i := 0; i < 100; i++ {
+		if response, err = client.Get(url); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.NoError(t, err)
+	data, err := ioutil.ReadAll(response.Body)
+	require.NoError(t, err)
+	require.NoError(t, response.Body.Close())
+	require.Equal(t, 2, response.ProtoMajor)
+	require.Equal(t, "2 client", string(data))
+
+	// the client certificate is required
+	_, err = newClient().Get(url)
+	require.Error(t, err)
+
+	cancel()
+	require.NoError(t, <-errC)
+}
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	der  []byte
+}
+
+// newTestCert returns a new certificate for commonName signed by parent,
+// or a self-signed CA certificate if parent is nil.
+func newTestCert(t *testing.T, commonName string, parent *testCert) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+	signerCert, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage |= x509.KeyUsageCertSign
+	} else {
+		signerCert, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signerCert, &key.PublicKey, signerKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCert{cert, key, der}
+}
+
+func (c *testCert) tlsCertificate() tls.Certificate {
+	return tls.Certificate{
+		Certificate: [][]byte{c.der},
+		PrivateKey:  c.key,
+	}
+}
+
+// write writes the PEM-encoded certificate and key to name.pem and name-key.pem
+// in dirPath, and returns their paths.
+func (c *testCert) write(t *testing.T, dirPath string, name string) (string, string) {
+	keyDER, err := x509.MarshalECPrivateKey(c.key)
+	require.NoError(t, err)
+	certFile := filepath.Join(dirPath, name+".pem")
+	keyFile := filepath.Join(dirPath, name+"-key.pem")
+	require.NoError(t, ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.der}), 0600))
+	require.NoError(t, ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+	return certFile, keyFile
+}
+
+func testLeafCommonName(t *testing.T, certReloader *certReloader) string {
+	cert, err := certReloader.GetCertificate(nil)
+	require.NoError(t, err)
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+	return leaf.Subject.CommonName
+}
+
+func testTempDir(t *testing.T) string {
+	dirPath, err := ioutil.TempDir("", "pkghttp")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = os.RemoveAll(dirPath)
+	})
+	return dirPath
+}
@@ -0,0 +1,217 @@
+package pkghttp
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.pedge.io/lion/proto"
+	"go.pedge.io/pb/go/google/protobuf"
+)
+
+var idempotentMethods = map[string]bool{
+	"DELETE":  true,
+	"GET":     true,
+	"HEAD":    true,
+	"OPTIONS": true,
+	"PUT":     true,
+	"TRACE":   true,
+}
+
+type transport struct {
+	opts       TransportOptions
+	callLogger *callLogger
+}
+
+func newTransport(opts TransportOptions) *transport {
+	if opts.Base == nil {
+		opts.Base = http.DefaultTransport
+	}
+	if opts.MaxAttempts == 0 {
+		opts.MaxAttempts = 3
+	}
+	if opts.InitialBackoff == 0 {
+		opts.InitialBackoff = 100 * time.Millisecond
+	}
+	if opts.MaxBackoff == 0 {
+		opts.MaxBackoff = 2 * time.Second
+	}
+	return &transport{
+		opts,
+		newCallLogger(
+			CallLogOptions{
+				RedactHeaders:  opts.RedactHeaders,
+				RedactFormKeys: opts.RedactQueryKeys,
+			},
+		),
+	}
+}
+
+func (t *transport) RoundTrip(request *http.Request) (*http.Response, error) {
+	requestID := request.Header.Get(RequestIDHeader)
+	if requestID == "" {
+		if requestID = RequestIDFromContext(request.Context()); requestID != "" {
+			// a RoundTripper must not modify the request
+			request = request.WithContext(request.Context())
+			request.Header = cloneHeader(request.Header)
+			request.Header.Set(RequestIDHeader, requestID)
+		}
+	}
+	maxAttempts := 1
+	if t.isRetryable(request) {
+		maxAttempts = t.opts.MaxAttempts
+	}
+	backoff := t.opts.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		if attempt > 1 && request.GetBody != nil {
+			body, err := request.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			request = request.WithContext(request.Context())
+			request.Body = body
+		}
+		response, err := t.roundTrip(request, requestID, attempt)
+		if attempt >= maxAttempts || !shouldRetry(response, err) {
+			return response, err
+		}
+		if response != nil {
+			// drain the body so that the connection can be reused
+			_, _ = io.Copy(ioutil.Discard, io.LimitReader(response.Body, 4096))
+			_ = response.Body.Close()
+		}
+		select {
+		case <-time.After(jitter(backoff)):
+		case <-request.Context().Done():
+			return nil, request.Context().Err()
+		}
+		backoff *= 2
+		if backoff > t.opts.MaxBackoff {
+			backoff = t.opts.MaxBackoff
+		}
+	}
+}
+
+func (t *transport) roundTrip(request *http.Request, requestID string, attempt int) (*http.Response, error) {
+	start := time.Now()
+	var cancel context.CancelFunc
+	if timeout := t.timeout(request.URL); timeout > 0 {
+		var ctx context.Context
+		ctx, cancel = context.WithTimeout(request.Context(), timeout)
+		request = request.WithContext(ctx)
+	}
+	response, err := t.opts.Base.RoundTrip(request)
+	clientCall := &ClientCall{
+		Method:        request.Method,
+		Url:           t.redactURL(request.URL),
+		RequestHeader: t.callLogger.headerMap(request.Header),
+		Duration:      google_protobuf.DurationToProto(time.Since(start)),
+		Error:         errorString(err),
+		RequestId:     requestID,
+		Attempt:       uint32(attempt),
+	}
+	if response != nil {
+		clientCall.StatusCode = uint32(response.StatusCode)
+		clientCall.ResponseHeader = t.callLogger.headerMap(response.Header)
+	}
+	if err != nil {
+		protolion.Error(clientCall)
+	} else {
+		protolion.Info(clientCall)
+	}
+	if cancel != nil {
+		if err != nil {
+			cancel()
+		} else {
+			// the timeout applies until the body is closed
+			response.Body = &cancelReadCloser{response.Body, cancel}
+		}
+	}
+	return response, err
+}
+
+func (t *transport) timeout(u *url.URL) time.Duration {
+	if timeout, ok := t.opts.HostTimeouts[u.Host]; ok {
+		return timeout
+	}
+	if timeout, ok := t.opts.HostTimeouts[u.Hostname()]; ok {
+		return timeout
+	}
+	return t.opts.Timeout
+}
+
+func (t *transport) isRetryable(request *http.Request) bool {
+	if t.opts.MaxAttempts <= 1 {
+		return false
+	}
+	if !idempotentMethods[request.Method] && request.Header.Get("Idempotency-Key") == "" {
+		return false
+	}
+	// the body must be able to be sent again
+	return request.Body == nil || request.Body == http.NoBody || request.GetBody != nil
+}
+
+func (t *transport) redactURL(u *url.URL) string {
+	redacted := *u
+	if redacted.User != nil {
+		if _, ok := redacted.User.Password(); ok {
+			redacted.User = url.UserPassword(redacted.User.Username(), redactedValue)
+		}
+	}
+	if redacted.RawQuery != "" {
+		query := redacted.Query()
+		for key := range query {
+			if t.callLogger.redactFormKeys[strings.ToLower(key)] {
+				query[key] = []string{redactedValue}
+			}
+		}
+		redacted.RawQuery = query.Encode()
+	}
+	return redacted.String()
+}
+
+// shouldRetry returns true for errors and for statuses that mean the
+// request may succeed if sent again.
+func shouldRetry(response *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	switch response.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+type cancelReadCloser struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelReadCloser) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
+
+func cloneHeader(header http.Header) http.Header {
+	clone := make(http.Header, len(header))
+	for key, values := range header {
+		clone[key] = append([]string(nil), values...)
+	}
+	return clone
+}
+
+func jitter(backoff time.Duration) time.Duration {
+	half := int64(backoff / 2)
+	if half <= 0 {
+		return backoff
+	}
+	return time.Duration(half + rand.Int63n(half+1))
+}
@@ -1,52 +1,87 @@
 package pkghttp
 
-import "net/http"
+import (
+	"bufio"
+	"net"
+	"net/http"
+)
 
 type wrapperResponseWriter interface {
 	http.ResponseWriter
 	StatusCode() int
 	WriteError() error
+	// Written returns true if the response has started, in which case the
+	// status code can no longer be changed.
+	Written() bool
+}
+
+// newWrapperResponseWriter returns a wrapperResponseWriter for responseWriter, and
+// the http.ResponseWriter to pass to the next handler, which is the wrapperResponseWriter
+// with the same optional interfaces as responseWriter.
+func newWrapperResponseWriter(responseWriter http.ResponseWriter) (wrapperResponseWriter, http.ResponseWriter) {
+	base := newWrapperResponseWrite(responseWriter)
+	// flushing or hijacking starts the response as well
+	var flusher http.Flusher
+	if responseFlusher, ok := responseWriter.(http.Flusher); ok {
+		flusher = &wrapperFlusher{responseFlusher, base}
+	}
+	var hijacker http.Hijacker
+	if responseHijacker, ok := responseWriter.(http.Hijacker); ok {
+		hijacker = &wrapperHijacker{responseHijacker, base}
+	}
+	return base, withOptionalInterfaces(base, responseWriter, flusher, hijacker)
 }
 
-func newWrapperResponseWriter(responseWriter http.ResponseWriter) wrapperResponseWriter {
-	flusher, flush := responseWriter.(http.Flusher)
-	hijacker, hijack := responseWriter.(http.Hijacker)
+// withOptionalInterfaces returns base, a wrapper of responseWriter, with the same optional
+// interfaces as responseWriter, so that a handler can still type assert for them.
+// The http.Flusher and http.Hijacker of base are flusher and hijacker, which must be set
+// if responseWriter has them, and the http.CloseNotifier of base is the one of responseWriter.
+func withOptionalInterfaces(
+	base http.ResponseWriter,
+	responseWriter http.ResponseWriter,
+	flusher http.Flusher,
+	hijacker http.Hijacker,
+) http.ResponseWriter {
+	_, flush := responseWriter.(http.Flusher)
+	_, hijack := responseWriter.(http.Hijacker)
 	closeNotifier, closeNotify := responseWriter.(http.CloseNotifier)
 	if flush {
 		if hijack {
 			if closeNotify {
-				return newWrapperResponseWriteFlushHijackCloseNotify(responseWriter, flusher, hijacker, closeNotifier)
+				return newResponseWriteFlushHijackCloseNotify(base, flusher, hijacker, closeNotifier)
 			}
-			return newWrapperResponseWriteFlushHijack(responseWriter, flusher, hijacker)
+			return newResponseWriteFlushHijack(base, flusher, hijacker)
 		}
 		if closeNotify {
-			return newWrapperResponseWriteFlushCloseNotify(responseWriter, flusher, closeNotifier)
+			return newResponseWriteFlushCloseNotify(base, flusher, closeNotifier)
 		}
-		return newWrapperResponseWriteFlush(responseWriter, flusher)
+		return newResponseWriteFlush(base, flusher)
 	}
 	if hijack {
 		if closeNotify {
-			return newWrapperResponseWriteHijackCloseNotify(responseWriter, hijacker, closeNotifier)
+			return newResponseWriteHijackCloseNotify(base, hijacker, closeNotifier)
 		}
-		return newWrapperResponseWriteHijack(responseWriter, hijacker)
+		return newResponseWriteHijack(base, hijacker)
 	}
 	if closeNotify {
-		return newWrapperResponseWriteCloseNotify(responseWriter, closeNotifier)
+		return newResponseWriteCloseNotify(base, closeNotifier)
 	}
-	return newWrapperResponseWrite(responseWriter)
+	return base
 }
 
-func newWrapperResponseWrite(responseWriter http.ResponseWriter) wrapperResponseWriter {
-	return &wrapperResponseWrite{responseWriter, 0, nil}
+func newWrapperResponseWrite(responseWriter http.ResponseWriter) *wrapperResponseWrite {
+	return &wrapperResponseWrite{responseWriter, 0, nil, false}
 }
 
 type wrapperResponseWrite struct {
 	http.ResponseWriter
 	statusCode int
 	writeError error
+	written    bool
 }
 
 func (w *wrapperResponseWrite) Write(p []byte) (int, error) {
+	w.written = true
 	n, err := w.ResponseWriter.Write(p)
 	w.writeError = err
 	return n, err
@@ -54,6 +89,7 @@ func (w *wrapperResponseWrite) Write(p []byte) (int, error) {
 
 func (w *wrapperResponseWrite) WriteHeader(statusCode int) {
 	w.statusCode = statusCode
+	w.written = true
 	w.ResponseWriter.WriteHeader(statusCode)
 }
 
@@ -65,120 +101,147 @@ func (w *wrapperResponseWrite) WriteError() error {
 	return w.writeError
 }
 
-type wrapperResponseWriteFlush struct {
-	wrapperResponseWriter
+func (w *wrapperResponseWrite) Written() bool {
+	return w.written
+}
+
+type wrapperFlusher struct {
 	http.Flusher
+	base *wrapperResponseWrite
 }
 
-func newWrapperResponseWriteFlush(
-	responseWriter http.ResponseWriter,
+func (f *wrapperFlusher) Flush() {
+	f.base.written = true
+	f.Flusher.Flush()
+}
+
+type wrapperHijacker struct {
+	http.Hijacker
+	base *wrapperResponseWrite
+}
+
+func (h *wrapperHijacker) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, readWriter, err := h.Hijacker.Hijack()
+	if err == nil {
+		h.base.written = true
+	}
+	return conn, readWriter, err
+}
+
+type responseWriteFlush struct {
+	http.ResponseWriter
+	http.Flusher
+}
+
+func newResponseWriteFlush(
+	base http.ResponseWriter,
 	flusher http.Flusher,
-) wrapperResponseWriter {
-	return &wrapperResponseWriteFlush{
-		newWrapperResponseWrite(responseWriter),
+) http.ResponseWriter {
+	return &responseWriteFlush{
+		base,
 		flusher,
 	}
 }
 
-type wrapperResponseWriteHijack struct {
-	wrapperResponseWriter
+type responseWriteHijack struct {
+	http.ResponseWriter
 	http.Hijacker
 }
 
-func newWrapperResponseWriteHijack(
-	responseWriter http.ResponseWriter,
+func newResponseWriteHijack(
+	base http.ResponseWriter,
 	hijacker http.Hijacker,
-) wrapperResponseWriter {
-	return &wrapperResponseWriteHijack{
-		newWrapperResponseWrite(responseWriter),
+) http.ResponseWriter {
+	return &responseWriteHijack{
+		base,
 		hijacker,
 	}
 }
 
-type wrapperResponseWriteCloseNotify struct {
-	wrapperResponseWriter
+type responseWriteCloseNotify struct {
+	http.ResponseWriter
 	http.CloseNotifier
 }
 
-func newWrapperResponseWriteCloseNotify(
-	responseWriter http.ResponseWriter,
+func newResponseWriteCloseNotify(
+	base http.ResponseWriter,
 	closeNotifier http.CloseNotifier,
-) wrapperResponseWriter {
-	return &wrapperResponseWriteCloseNotify{
-		newWrapperResponseWrite(responseWriter),
+) http.ResponseWriter {
+	return &responseWriteCloseNotify{
+		base,
 		closeNotifier,
 	}
 }
 
-type wrapperResponseWriteFlushHijack struct {
-	wrapperResponseWriter
+type responseWriteFlushHijack struct {
+	http.ResponseWriter
 	http.Flusher
 	http.Hijacker
 }
 
-func newWrapperResponseWriteFlushHijack(
-	responseWriter http.ResponseWriter,
+func newResponseWriteFlushHijack(
+	base http.ResponseWriter,
 	flusher http.Flusher,
 	hijacker http.Hijacker,
-) wrapperResponseWriter {
-	return &wrapperResponseWriteFlushHijack{
-		newWrapperResponseWrite(responseWriter),
+) http.ResponseWriter {
+	return &responseWriteFlushHijack{
+		base,
 		flusher,
 		hijacker,
 	}
 }
 
-type wrapperResponseWriteFlushCloseNotify struct {
-	wrapperResponseWriter
+type responseWriteFlushCloseNotify struct {
+	http.ResponseWriter
 	http.Flusher
 	http.CloseNotifier
 }
 
-func newWrapperResponseWriteFlushCloseNotify(
-	responseWriter http.ResponseWriter,
+func newResponseWriteFlushCloseNotify(
+	base http.ResponseWriter,
 	flusher http.Flusher,
 	closeNotifier http.CloseNotifier,
-) wrapperResponseWriter {
-	return &wrapperResponseWriteFlushCloseNotify{
-		newWrapperResponseWrite(responseWriter),
+) http.ResponseWriter {
+	return &responseWriteFlushCloseNotify{
+		base,
 		flusher,
 		closeNotifier,
 	}
 }
 
-type wrapperResponseWriteHijackCloseNotify struct {
-	wrapperResponseWriter
+type responseWriteHijackCloseNotify struct {
+	http.ResponseWriter
 	http.Hijacker
 	http.CloseNotifier
 }
 
-func newWrapperResponseWriteHijackCloseNotify(
-	responseWriter http.ResponseWriter,
+func newResponseWriteHijackCloseNotify(
+	base http.ResponseWriter,
 	hijacker http.Hijacker,
 	closeNotifier http.CloseNotifier,
-) wrapperResponseWriter {
-	return &wrapperResponseWriteHijackCloseNotify{
-		newWrapperResponseWrite(responseWriter),
+) http.ResponseWriter {
+	return &responseWriteHijackCloseNotify{
+		base,
 		hijacker,
 		closeNotifier,
 	}
 }
 
-type wrapperResponseWriteFlushHijackCloseNotify struct {
-	wrapperResponseWriter
+type responseWriteFlushHijackCloseNotify struct {
+	http.ResponseWriter
 	http.Flusher
 	http.Hijacker
 	http.CloseNotifier
 }
 
-func newWrapperResponseWriteFlushHijackCloseNotify(
-	responseWriter http.ResponseWriter,
+func newResponseWriteFlushHijackCloseNotify(
+	base http.ResponseWriter,
 	flusher http.Flusher,
 	hijacker http.Hijacker,
 	closeNotifier http.CloseNotifier,
-) wrapperResponseWriter {
-	return &wrapperResponseWriteFlushHijackCloseNotify{
-		newWrapperResponseWrite(responseWriter),
+) http.ResponseWriter {
+	return &responseWriteFlushHijackCloseNotify{
+		base,
 		flusher,
 		hijacker,
 		closeNotifier,
@@ -0,0 +1,41 @@
+package pkghttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithOptionalInterfaces(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	for _, responseWriter := range []http.ResponseWriter{
+		recorder,
+		&testHijackResponseWriter{recorder},
+		struct{ http.ResponseWriter }{recorder},
+	} {
+		_, flush := responseWriter.(http.Flusher)
+		_, hijack := responseWriter.(http.Hijacker)
+		_, closeNotify := responseWriter.(http.CloseNotifier)
+		for _, wrapped := range []http.ResponseWriter{
+			func() http.ResponseWriter {
+				_, wrapped := newWrapperResponseWriter(responseWriter)
+				return wrapped
+			}(),
+			withOptionalInterfaces(
+				&compressResponseWriter{responseWriter: responseWriter},
+				responseWriter,
+				compressFlusher{},
+				compressHijacker{},
+			),
+		} {
+			_, ok := wrapped.(http.Flusher)
+			require.Equal(t, flush, ok)
+			_, ok = wrapped.(http.Hijacker)
+			require.Equal(t, hijack, ok)
+			_, ok = wrapped.(http.CloseNotifier)
+			require.Equal(t, closeNotify, ok)
+		}
+	}
+}